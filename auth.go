@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type token struct {
+	username string
+	value    string
+	resetAt  time.Time
+}
+
+// tokenPool hands out tokens round-robin so requests spread across the rate
+// limit of every token; a token that runs out is skipped until its reset.
+type tokenPool struct {
+	mu     sync.Mutex
+	tokens []*token
+	next   int
+}
+
+var tokens = newTokenPool([]string{os.Getenv("GITHUB_TOKEN")})
+
+func newTokenPool(values []string) *tokenPool {
+	pool := &tokenPool{}
+	for _, v := range values {
+		pool.tokens = append(pool.tokens, &token{
+			username: os.Getenv("GITHUB_USERNAME"), value: v,
+		})
+	}
+	return pool
+}
+
+// loadTokens reads tokens from a file (one per line) when given, otherwise
+// from the comma-separated GITHUB_TOKENS, falling back to GITHUB_TOKEN and then
+// ~/.netrc. With useGitCredential the token comes from git's credential helper
+// instead.
+func loadTokens(path string, useGitCredential bool) (*tokenPool, error) {
+	var values []string
+
+	if useGitCredential {
+		username, password, err := gitCredential()
+		if err != nil {
+			return nil, err
+		}
+
+		if username == "" {
+			username = os.Getenv("GITHUB_USERNAME")
+		}
+
+		return &tokenPool{
+			tokens: []*token{{username: username, value: password}},
+		}, nil
+	}
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				values = append(values, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, v := range strings.Split(os.Getenv("GITHUB_TOKENS"), ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+
+	if len(values) == 0 && os.Getenv("GITHUB_TOKEN") == "" {
+		login, password, err := netrcCredential()
+		if err != nil {
+			return nil, err
+		}
+
+		if password != "" {
+			return &tokenPool{
+				tokens: []*token{{username: login, value: password}},
+			}, nil
+		}
+	}
+
+	if len(values) == 0 {
+		values = []string{os.Getenv("GITHUB_TOKEN")}
+	}
+
+	return newTokenPool(values), nil
+}
+
+// pick returns the next token that is not waiting on a reset. When every token
+// is exhausted the one resetting soonest is returned.
+func (p *tokenPool) pick() *token {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	soonest := p.tokens[p.next%len(p.tokens)]
+	for range p.tokens {
+		t := p.tokens[p.next%len(p.tokens)]
+		p.next++
+
+		if !t.resetAt.After(now) {
+			return t
+		}
+		if t.resetAt.Before(soonest.resetAt) {
+			soonest = t
+		}
+	}
+
+	return soonest
+}
+
+// available reports whether any token can currently make requests.
+func (p *tokenPool) available() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for _, t := range p.tokens {
+		if !t.resetAt.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// minPark is the least a rate-limited token is parked for, so a reset that
+// has already passed, or a clock running ahead of GitHub's, can't have it
+// picked again straight away
+const minPark = 5 * time.Second
+
+// size is how many tokens are in the pool
+func (p *tokenPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.tokens)
+}
+
+// exhaust parks a token until the reset time reported by the response.
+func (p *tokenPool) exhaust(t *token, resp *http.Response) {
+	now := time.Now()
+	resetAt := now.Add(time.Minute)
+	if reset, err := strconv.ParseInt(
+		resp.Header.Get("X-RateLimit-Reset"), 10, 64,
+	); err == nil {
+		resetAt = time.Unix(reset, 0)
+	}
+	if earliest := now.Add(minPark); resetAt.Before(earliest) {
+		resetAt = earliest
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.resetAt = resetAt
+}
+
+var (
+	sharedClient     *http.Client
+	sharedClientOnce sync.Once
+)
+
+// newClient returns the client every org, repo and page is fetched with, made
+// on first use once the flags are parsed. Its requests give up after
+// --request-timeout, so a hung connection can't hold on to a worker. It keeps
+// the default transport, and with it a single connection pool for the run,
+// which asks for gzip and decompresses it as long as nothing sets
+// Accept-Encoding itself. Tokens and the rate limit are tracked run-wide too.
+func newClient() *http.Client {
+	sharedClientOnce.Do(func() {
+		sharedClient = &http.Client{Timeout: *requestTimeout}
+	})
+	return sharedClient
+}
+
+// rateStatus is the lowest rate limit remaining seen over the run
+type rateStatus struct {
+	mu        sync.Mutex
+	seen      bool
+	remaining int
+	limit     int
+	resetAt   time.Time
+}
+
+var rateLimit rateStatus
+
+// observe records the response's rate limit when it is the lowest so far.
+// Responses replayed from the cache don't carry one.
+func (s *rateStatus) observe(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen && remaining >= s.remaining {
+		return
+	}
+	s.seen = true
+	s.remaining, s.limit, s.resetAt = remaining, limit, time.Unix(reset, 0)
+}
+
+// String reads like "Rate limit: 4231/5000, resets at 14:32"
+func (s *rateStatus) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.seen {
+		return "Rate limit: unknown"
+	}
+	return fmt.Sprintf(
+		"Rate limit: %d/%d, resets at %s", s.remaining, s.limit,
+		inZone(s.resetAt).Format("15:04"),
+	)
+}
+
+func rateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden &&
+		resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+// ssoError explains a 403 from an org enforcing SAML single sign-on that the
+// token has not been authorized for, or returns nil for any other response.
+// GitHub marks these with an X-GitHub-SSO header such as
+// "required; url=https://github.com/orgs/acme/sso?authorization_request=...".
+func ssoError(org string, resp *http.Response) error {
+	sso := resp.Header.Get("X-GitHub-SSO")
+	if resp.StatusCode != http.StatusForbidden ||
+		!strings.HasPrefix(sso, "required") {
+		return nil
+	}
+
+	msg := "token is not authorized for the SAML SSO of " + org
+	for _, part := range strings.Split(sso, ";") {
+		if u := strings.TrimSpace(part); strings.HasPrefix(u, "url=") {
+			return fmt.Errorf(
+				"%s, authorize it at %s", msg, strings.TrimPrefix(u, "url="),
+			)
+		}
+	}
+
+	return fmt.Errorf(
+		"%s, authorize it under Configure SSO in the token settings", msg,
+	)
+}
+
+// errOutOfScope is returned for a repo a fine-grained personal access token
+// wasn't granted, so it can be skipped rather than counted as inactive
+var errOutOfScope = errors.New("not accessible by the fine-grained token")
+
+// fineGrained reports whether resp is a fine-grained personal access token
+// refusing a resource outside what it was granted. GitHub only tells these
+// 403s apart by their message, such as "Resource not accessible by personal
+// access token". It reads the body, which is left for the caller to close.
+func fineGrained(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return strings.Contains(body.Message, "personal access token")
+}
+
+// fineGrainedError explains a 403 listing the repos of an org that a
+// fine-grained personal access token wasn't granted, or returns nil for any
+// other response
+func fineGrainedError(org string, resp *http.Response) error {
+	if !fineGrained(resp) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"the fine-grained token has no access to %s; pick %s as its resource "+
+			"owner in the token settings, and have an org owner approve it if "+
+			"%s requires approval",
+		org, org, org,
+	)
+}
+
+// requestSlots bounds the requests in flight across every org scanned at once
+// under --parallel-orgs, so together they stay within --concurrency; it is nil,
+// and unbounded, otherwise.
+var requestSlots chan struct{}
+
+// doRequest authorizes the request with a token from the pool, pins the API
+// version, identifies the tool, adds any --header and sends it. A rate-limited
+// response parks that token and the request is sent again with the next one,
+// so long as another token is available, once per token in the pool at most.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	req.Header.Set("X-GitHub-Api-Version", *apiVersion)
+	req.Header.Set("User-Agent", *userAgent)
+
+	for tries := 1; ; tries++ {
+		t := tokens.pick()
+		req.SetBasicAuth(t.username, t.value)
+		extraHeaders.apply(req)
+
+		atomic.AddInt64(&requests.Total, 1)
+		if requestSlots != nil {
+			requestSlots <- struct{}{}
+		}
+		resp, err := doCached(client, req)
+		if requestSlots != nil {
+			<-requestSlots
+		}
+		if err != nil {
+			return resp, err
+		}
+
+		debugResponse(req, resp)
+		checkScopes(resp)
+
+		rateLimit.observe(resp)
+		if !rateLimited(resp) {
+			return resp, nil
+		}
+		atomic.AddInt64(&requests.RateLimited, 1)
+
+		tokens.exhaust(t, resp)
+		if tries >= tokens.size() || !tokens.available() {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		if req.GetBody != nil {
+			req.Body, _ = req.GetBody()
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExhaustPastReset(t *testing.T) {
+	pool := newTokenPool([]string{"a"})
+	tok := pool.pick()
+
+	resp := &http.Response{Header: http.Header{}}
+	past := time.Now().Add(-time.Hour).Unix()
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(past, 10))
+
+	pool.exhaust(tok, resp)
+	if pool.available() {
+		t.Errorf("token available right after a reset in the past")
+	}
+}
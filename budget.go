@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type rateLimits struct {
+	Resources struct {
+		Core struct {
+			Remaining int   `json:"remaining"`
+			Reset     int64 `json:"reset"`
+		} `json:"core"`
+	} `json:"resources"`
+}
+
+// budget is what the run may spend under --check-budget. It is set by the
+// first check, as the total of requests.Total at which the rate limit runs out,
+// so every org after it is checked against what the run has spent so far.
+var budget struct {
+	sync.Mutex
+	limit int64
+	reset time.Time
+
+	// reserved holds the stats requests of orgs that passed the check and are
+	// still fetching them, which under --parallel-orgs haven't all been made
+	reserved int64
+}
+
+// checkBudget fails when the rate limit left won't cover fetching the repos'
+// stats, on top of what the run has spent and the orgs still fetching theirs
+// will, rather than running out halfway through. Passing reserves the repos'
+// requests until releaseBudget. The rate limit is asked of GitHub on the first
+// check, which doesn't count against it, and only for the token the check is
+// sent with.
+func checkBudget(org string, repos []*repo) error {
+	budget.Lock()
+	defer budget.Unlock()
+
+	if budget.limit == 0 {
+		remaining, reset, err := fetchRateLimit()
+		if err != nil {
+			return err
+		}
+		budget.limit = atomic.LoadInt64(&requests.Total) + int64(remaining)
+		budget.reset = reset
+	}
+
+	needed := int64(statsRequests(repos))
+	left := budget.limit - atomic.LoadInt64(&requests.Total) - budget.reserved
+	if left < needed {
+		return fmt.Errorf(
+			"rate limit too low for %s: %d requests left, at least %d needed "+
+				"for %d repos; it resets at %s",
+			org, max(left, 0), needed, len(repos),
+			inZone(budget.reset).Format("15:04"),
+		)
+	}
+	budget.reserved += needed
+
+	log.Printf(
+		"Rate limit budget: %d requests left, at least %d needed", left, needed,
+	)
+	return nil
+}
+
+// releaseBudget gives back what checkBudget reserved for the repos once their
+// stats are fetched, and spent
+func releaseBudget(repos []*repo) {
+	budget.Lock()
+	defer budget.Unlock()
+
+	budget.reserved -= int64(statsRequests(repos))
+}
+
+// fetchRateLimit asks GitHub how many requests the token has left and when
+// that resets
+func fetchRateLimit() (int, time.Time, error) {
+	req, _ := http.NewRequest("GET", "https://api.github.com/rate_limit", nil)
+
+	var limits rateLimits
+	resp, err := getJSON(newClient(), req, &limits)
+	if resp == nil {
+		return 0, time.Time{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, time.Time{}, fmt.Errorf(
+			"checking the rate limit failed: %s", resp.Status,
+		)
+	}
+
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf(
+			"unmarshaling the rate limit failed: %s", err,
+		)
+	}
+
+	core := limits.Resources.Core
+	return core.Remaining, time.Unix(core.Reset, 0), nil
+}
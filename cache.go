@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry is a response saved to disk so it can be revalidated with its
+// ETag on the next run; a 304 does not count against the rate limit.
+type cacheEntry struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// cachedHeaders are kept with the payload; everything else, like the rate
+// limit, comes fresh from the 304.
+var cachedHeaders = []string{"Content-Type", "Link"}
+
+// cachePath names the entry for the request after its URL and the credentials
+// it went out with, so tokens with different access never share an entry;
+// both are hashed, so the token isn't written to disk.
+func cachePath(req *http.Request) string {
+	key := req.Header.Get("Authorization") + " " + req.URL.String()
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(*cacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func readCacheEntry(path string) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.ETag == "" {
+		return nil
+	}
+
+	return &entry
+}
+
+func writeCacheEntry(path string, entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// doCached sends a GET with If-None-Match when there is a cached response for
+// it, answering a 304 with the cached body. Fresh responses carrying an ETag
+// are saved for next time.
+func doCached(client *http.Client, req *http.Request) (*http.Response, error) {
+	if *cacheDir == "" || req.Method != "GET" {
+		return client.Do(req)
+	}
+
+	path := cachePath(req)
+	entry := readCacheEntry(path)
+	if entry != nil {
+		req.Header.Set("If-None-Match", entry.ETag)
+	} else {
+		req.Header.Del("If-None-Match")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+
+		for _, key := range cachedHeaders {
+			resp.Header.Del(key)
+			for _, v := range entry.Header[key] {
+				resp.Header.Add(key, v)
+			}
+		}
+
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Body = io.NopCloser(bytes.NewReader(entry.Body))
+
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		fresh := &cacheEntry{
+			ETag:   resp.Header.Get("ETag"),
+			Header: http.Header{},
+			Body:   body,
+		}
+		for _, key := range cachedHeaders {
+			if v, ok := resp.Header[key]; ok {
+				fresh.Header[key] = v
+			}
+		}
+
+		if err := writeCacheEntry(path, fresh); err != nil {
+			log.Printf("Warning: caching %s failed: %v", req.URL, err)
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return resp, nil
+}
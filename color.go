@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// useColor decides whether output written to w should be colorized; in auto
+// mode that is only when w is a terminal and NO_COLOR (https://no-color.org)
+// isn't set. --color=always still colorizes with NO_COLOR set.
+func useColor(w io.Writer) bool {
+	if *noColor {
+		return false
+	}
+
+	switch *colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w writes to a terminal rather than a file or pipe
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func paint(code, s string) string {
+	return code + s + ansiReset
+}
+
+// countColor grades a count against the largest in the report so the busiest
+// repos stand out from the quiet ones.
+func countColor(count, highest int) string {
+	switch {
+	case count*3 >= highest*2:
+		return ansiGreen
+	case count*3 >= highest:
+		return ansiYellow
+	default:
+		return ansiRed
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type branch struct {
+	Name string `json:"name"`
+}
+
+type commit struct {
+	SHA string `json:"sha"`
+}
+
+// errStopPaging can be returned by a forEachPage callback to stop listing
+// without an error once it has seen everything it needs
+var errStopPaging = errors.New("stop paging")
+
+// forEachPage requests every page of a listing, following rel="next" links,
+// and hands each page's body to f for decoding. Each page is retried like the
+// org listing is.
+func forEachPage(
+	client *http.Client, pageURL string, f func(*json.Decoder) error,
+) error {
+	for pageURL != "" {
+		req, _ := http.NewRequest("GET", pageURL, nil)
+
+		resp, err := doWithRetry(client, req)
+		if err != nil {
+			return err
+		}
+
+		// Listing commits of an empty repository conflicts; nothing to list
+		if resp.StatusCode == http.StatusConflict {
+			resp.Body.Close()
+			return nil
+		}
+
+		if fineGrained(resp) {
+			resp.Body.Close()
+			return errOutOfScope
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf(
+				"fetching page failed: %s for %s", resp.Status, pageURL,
+			)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		err = f(json.NewDecoder(bytes.NewReader(body)))
+		if err == errStopPaging {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf(
+				"unmarshaling page failed: %s for %s",
+				bodyError(err, resp, body), pageURL,
+			)
+		}
+
+		pageURL = ""
+		if next, ok := parseLink(resp.Header.Get("link"))["next"]; ok {
+			pageURL = next.String()
+		}
+	}
+
+	return nil
+}
+
+// fetchBranchCommits counts the distinct commits on every branch within the
+// last six months using the commits listing rather than the statistics
+// endpoint, which only covers the default branch. This takes a request per page
+// of commits per branch, so it is much slower.
+func fetchBranchCommits(r *repo) *report {
+	client := newClient()
+
+	var branches []string
+	err := forEachPage(
+		client, "https://api.github.com/repos/"+r.Name+"/branches?per_page=100",
+		func(dec *json.Decoder) error {
+			var page []*branch
+			err := dec.Decode(&page)
+			for _, b := range page {
+				branches = append(branches, b.Name)
+			}
+			return err
+		},
+	)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	return fetchCommitsOn(client, r, branches)
+}
+
+// fetchCommitsOn counts the distinct commits within the last six months across
+// the given branches, a request per page of commits per branch
+func fetchCommitsOn(client *http.Client, r *repo, branches []string) *report {
+	repoURL := "https://api.github.com/repos/" + r.Name
+
+	sixMonthsAgo, now := window()
+	start, since := windowStart(r, sixMonthsAgo)
+
+	seen := make(map[string]bool)
+	for _, b := range branches {
+		query := url.Values{}
+		query.Set("sha", b)
+		query.Set("since", start.Format(time.RFC3339))
+		query.Set("until", now.Format(time.RFC3339))
+		query.Set("per_page", "100")
+
+		err := forEachPage(
+			client, repoURL+"/commits?"+query.Encode(),
+			func(dec *json.Decoder) error {
+				var page []*commit
+				err := dec.Decode(&page)
+				for _, c := range page {
+					seen[c.SHA] = true
+				}
+				return err
+			},
+		)
+		if err != nil {
+			return &report{Name: r.Name, Error: err}
+		}
+	}
+
+	return &report{
+		Name: r.Name, Summary: len(seen), PushedAt: r.PushedAt, Since: since,
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+type contributor struct {
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Total int `json:"total"`
+	Weeks []struct {
+		Week    int64 `json:"w"`
+		Commits int   `json:"c"`
+	} `json:"weeks"`
+}
+
+// login is the contributor's GitHub login, empty for commits by authors
+// without an account
+func (c *contributor) login() string {
+	if c.Author == nil {
+		return ""
+	}
+	return c.Author.Login
+}
+
+// commitsInWindow adds up the contributor's commits within the window
+func (c *contributor) commitsInWindow(start, end time.Time) int {
+	var commits int
+	for _, w := range c.Weeks {
+		if inWindow(w.Week, start, end) {
+			commits += w.Commits
+		}
+	}
+	return commits
+}
+
+// fetchContributorStats gets the weekly commits of each of the repo's top 100
+// contributors, or none for an empty repo
+func fetchContributorStats(
+	client *http.Client, r *repo,
+) ([]*contributor, int, error) {
+	url := "https://api.github.com/repos/" + r.Name + "/stats/contributors"
+
+	var contributors []*contributor
+	status, tries, err := pollStats(client, url, &contributors)
+	if err != nil || status != http.StatusOK {
+		return nil, tries, err
+	}
+
+	for _, c := range contributors {
+		for _, w := range c.Weeks {
+			observeWeek(w.Week)
+		}
+	}
+
+	return contributors, tries, nil
+}
+
+// fetchContributors counts the authors with at least one commit to the repo
+// within the last six months.
+func fetchContributors(client *http.Client, r *repo) (int, error) {
+	contributors, _, err := fetchContributorStats(client, r)
+	if err != nil {
+		return 0, err
+	}
+
+	sixMonthsAgo, now := window()
+
+	var count int
+	for _, c := range contributors {
+		if c.commitsInWindow(sixMonthsAgo, now) > 0 {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// fetchHumanCommits counts commits within the window like fetchStat, but from
+// the contributors endpoint so authors matching --bot-pattern can be left out
+func fetchHumanCommits(r *repo) *report {
+	contributors, tries, err := fetchContributorStats(newClient(), r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	sixMonthsAgo, now := window()
+	start, since := windowStart(r, sixMonthsAgo)
+	if since != nil && !*strictWindow {
+		// Weeks start on Sunday; keep the one the repo was created in
+		start = start.AddDate(0, 0, -7)
+	}
+
+	var commits int
+	for _, c := range contributors {
+		if !botRegexp.MatchString(c.login()) {
+			commits += c.commitsInWindow(start, now)
+		}
+	}
+
+	return &report{
+		Name: r.Name, Summary: commits, PushedAt: r.PushedAt, Retries: tries,
+		Since: since,
+	}
+}
+
+// countContributors adds how many authors committed to the repo within the
+// window to its report, for --contributors
+func countContributors(r *repo, rep *report) {
+	if rep.Error != nil || rep.Summary == 0 {
+		return
+	}
+
+	count, err := fetchContributors(newClient(), r)
+	if err != nil {
+		rep.Error = err
+		return
+	}
+	rep.Contributors = count
+}
+
+// normalizeReport divides the repo's commits between its recent contributors
+func normalizeReport(r *repo, rep *report) {
+	if rep.Error != nil || rep.Summary == 0 {
+		return
+	}
+
+	count, err := fetchContributors(newClient(), r)
+	if err != nil {
+		rep.Error = err
+		return
+	}
+
+	rep.Contributors = count
+	if count > 0 {
+		rep.PerContributor = float64(rep.Summary) / float64(count)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitCredential asks git's configured credential helper for the github.com
+// login, the same one used when pushing over https, so no extra copy of the
+// token has to be kept around.
+func gitCredential() (username, password string, err error) {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=github.com\n\n")
+
+	// Fail rather than prompt when no helper has the credential
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf(
+			"git credential fill failed: %v %s", err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+
+	if password == "" {
+		return "", "", fmt.Errorf("git credential fill returned no password")
+	}
+
+	return username, password, nil
+}
+
+// netrcMachines are looked up in order; the API host is the more specific
+var netrcMachines = []string{"api.github.com", "github.com"}
+
+// netrcCredential reads the GitHub login from $NETRC or ~/.netrc. A missing
+// file or machine entry isn't an error, it just yields no credential.
+func netrcCredential() (login, password string, err error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	machines := parseNetrc(string(data))
+	for _, name := range netrcMachines {
+		if m, ok := machines[name]; ok && m.password != "" {
+			return m.login, m.password, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+type netrcMachine struct {
+	login    string
+	password string
+}
+
+// parseNetrc reads the machine entries of a netrc file. Macro definitions are
+// skipped up to the blank line that ends them.
+func parseNetrc(data string) map[string]*netrcMachine {
+	machines := make(map[string]*netrcMachine)
+
+	var current *netrcMachine
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := ""
+			if j+1 < len(fields) {
+				next = fields[j+1]
+			}
+
+			switch fields[j] {
+			case "machine":
+				current = &netrcMachine{}
+				machines[next] = current
+				j++
+			case "default":
+				current = nil
+			case "login":
+				if current != nil {
+					current.login = next
+				}
+				j++
+			case "password":
+				if current != nil {
+					current.password = next
+				}
+				j++
+			case "macdef":
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+
+	return machines
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+)
+
+// debugBody logs the size of a response body once it has been read, and
+// whether it came compressed, for --debug
+type debugBody struct {
+	io.ReadCloser
+	req  *http.Request
+	resp *http.Response
+	read int64
+}
+
+func (b *debugBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *debugBody) Close() error {
+	// The transport only decompresses transparently, and says so with
+	// Uncompressed, when Accept-Encoding is left for it to set
+	encoding := "uncompressed"
+	if b.resp.Uncompressed {
+		encoding = "gzip"
+	}
+
+	log.Printf(
+		"debug: %s %s: %s, %d bytes read, %s", b.req.Method, b.req.URL,
+		b.resp.Status, b.read, encoding,
+	)
+	return b.ReadCloser.Close()
+}
+
+// debugResponse wraps the response body to log its size under --debug
+func debugResponse(req *http.Request, resp *http.Response) {
+	if *debug {
+		resp.Body = &debugBody{ReadCloser: resp.Body, req: req, resp: resp}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// snippetLength is how much of a body that isn't JSON is quoted in errors
+const snippetLength = 200
+
+// decodeBody decodes the response's JSON body into v as it streams in, so a
+// large payload, like the contributors of a big repo, isn't also held whole. A
+// body that isn't JSON, like the HTML error page of a proxy, is described in
+// the error so it's clear what came back instead.
+func decodeBody(resp *http.Response, v interface{}) error {
+	var head snippetWriter
+	err := json.NewDecoder(io.TeeReader(resp.Body, &head)).Decode(v)
+	if err != nil {
+		return bodyError(err, resp, head.buf)
+	}
+	return nil
+}
+
+// snippetWriter keeps the first snippetLength bytes written to it, to quote in
+// errors, and drops the rest
+type snippetWriter struct {
+	buf []byte
+}
+
+func (w *snippetWriter) Write(p []byte) (int, error) {
+	if room := snippetLength - len(w.buf); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		w.buf = append(w.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+// bodyError adds the content type and the start of the body to a decode error
+func bodyError(err error, resp *http.Response, body []byte) error {
+	snippet := body
+	if len(snippet) >= snippetLength {
+		snippet = snippet[:snippetLength]
+		// Don't cut a multi-byte character in half
+		for len(snippet) > 0 && !utf8.Valid(snippet) {
+			snippet = snippet[:len(snippet)-1]
+		}
+	}
+
+	return fmt.Errorf(
+		"%w (got Content-Type %q: %q)", err,
+		resp.Header.Get("Content-Type"), snippet,
+	)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// readEnvelope loads a report saved with --format=json or --out-dir
+func readEnvelope(path string) (*jsonEnvelope, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var envelope jsonEnvelope
+	if err := json.NewDecoder(f).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("unmarshaling %s failed: %s", path, err)
+	}
+
+	return &envelope, nil
+}
+
+type repoChange struct {
+	name     string
+	old, new int
+
+	// errored is set when either report failed to fetch the repo, so its
+	// count isn't a change in activity
+	errored bool
+}
+
+// DiffReports compares two saved reports of the same org: which repos went up
+// or down, which became active and which went dormant. Repos missing from a
+// report count as having no commits in it; repos that errored in either are
+// listed apart.
+func DiffReports(oldPath, newPath string) error {
+	older, err := readEnvelope(oldPath)
+	if err != nil {
+		return err
+	}
+
+	newer, err := readEnvelope(newPath)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]*repoChange)
+	// Names match whatever their case, as reports of older runs may differ
+	change := func(name string) *repoChange {
+		key := strings.ToLower(name)
+		if counts[key] == nil {
+			counts[key] = &repoChange{name: key}
+		}
+		return counts[key]
+	}
+	for _, r := range older.Repos {
+		c := change(r.Name)
+		c.old, c.errored = r.Summary, r.State == "error"
+	}
+	for _, r := range newer.Repos {
+		c := change(r.Name)
+		c.new, c.errored = r.Summary, c.errored || r.State == "error"
+	}
+
+	var active, dormant, up, down, errored []*repoChange
+	for _, c := range counts {
+		switch {
+		case c.errored:
+			errored = append(errored, c)
+		case c.old == 0 && c.new > 0:
+			active = append(active, c)
+		case c.old > 0 && c.new == 0:
+			dormant = append(dormant, c)
+		case c.new > c.old:
+			up = append(up, c)
+		case c.new < c.old:
+			down = append(down, c)
+		}
+	}
+
+	org := newer.Org
+	writeChanges(stdout, org, "Up", up)
+	writeChanges(stdout, org, "Down", down)
+	writeChanges(stdout, org, "Newly active", active)
+	writeChanges(stdout, org, "Went dormant", dormant)
+	writeErrored(stdout, org, errored)
+
+	if len(up)+len(down)+len(active)+len(dormant)+len(errored) == 0 {
+		fmt.Fprintf(stdout, "\nNo changes for %s\n", org)
+	}
+
+	return nil
+}
+
+// writeChanges prints a section of the diff, biggest change first
+func writeChanges(w io.Writer, org, title string, changes []*repoChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a := changes[i].new - changes[i].old
+		b := changes[j].new - changes[j].old
+		if a < 0 {
+			a = -a
+		}
+		if b < 0 {
+			b = -b
+		}
+		if a != b {
+			return a > b
+		}
+		return changes[i].name < changes[j].name
+	})
+
+	fmt.Fprintf(w, "\n%s\n%s\n", title, strings.Repeat("-", len(title)))
+
+	for _, c := range changes {
+		fmt.Fprintf(
+			w, "%s: %d -> %d (%+d)\n", displayName(org, c.name), c.old, c.new,
+			c.new-c.old,
+		)
+	}
+}
+
+// writeErrored lists the repos that failed to fetch in either report, whose
+// counts can't be compared
+func writeErrored(w io.Writer, org string, changes []*repoChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].name < changes[j].name
+	})
+
+	fmt.Fprintln(w, "\nErrored")
+	fmt.Fprintln(w, "-------")
+
+	for _, c := range changes {
+		fmt.Fprintln(w, displayName(org, c.name))
+	}
+}
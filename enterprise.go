@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const enterpriseOrgsQuery = `
+query($slug: String!, $cursor: String) {
+  enterprise(slug: $slug) {
+    organizations(first: 100, after: $cursor) {
+      nodes { login }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+type enterpriseOrgsResponse struct {
+	Data struct {
+		Enterprise *struct {
+			Organizations struct {
+				Nodes []struct {
+					Login string `json:"login"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"organizations"`
+		} `json:"enterprise"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// ListEnterpriseOrgs returns the login of every organization belonging to the
+// enterprise. The REST API has no listing for this, so it goes through GraphQL.
+func ListEnterpriseOrgs(slug string) ([]string, error) {
+	client := newClient()
+
+	var orgs []string
+	var cursor *string
+	for {
+		body, _ := json.Marshal(map[string]interface{}{
+			"query":     enterpriseOrgsQuery,
+			"variables": map[string]interface{}{"slug": slug, "cursor": cursor},
+		})
+
+		req, _ := http.NewRequest(
+			"POST", "https://api.github.com/graphql", bytes.NewReader(body),
+		)
+
+		resp, err := doRequest(client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("listing enterprise orgs failed: %s", resp.Status)
+		}
+
+		var page enterpriseOrgsResponse
+		err = decodeBody(resp, &page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling enterprise orgs failed: %s", err)
+		}
+
+		if len(page.Errors) > 0 {
+			return nil, fmt.Errorf(
+				"listing enterprise orgs failed: %s", page.Errors[0].Message,
+			)
+		}
+
+		if page.Data.Enterprise == nil {
+			return nil, fmt.Errorf("enterprise %s not found", slug)
+		}
+
+		organizations := page.Data.Enterprise.Organizations
+		for _, node := range organizations.Nodes {
+			orgs = append(orgs, node.Login)
+		}
+
+		if !organizations.PageInfo.HasNextPage {
+			return orgs, nil
+		}
+
+		cursor = &organizations.PageInfo.EndCursor
+	}
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// version is stamped into release builds with -ldflags "-X main.version=..."
+var version = "dev"
+
+var (
+	reposLimit = flag.Int(
+		"repos-limit", 0, "only fetch stats for the N most recently pushed repos",
+	)
+	useGitCredential = flag.Bool(
+		"git-credential", false, "get the token from git's credential helper",
+	)
+	listOrgs = flag.Bool(
+		"list-orgs", false, "print the orgs the token can see and exit",
+	)
+	enterprise = flag.String(
+		"enterprise", "", "also report on every org under this enterprise slug",
+	)
+	format = flag.String(
+		"format", "text", "output format: text, json, json-map or html",
+	)
+	jsonPretty = flag.Bool(
+		"json-pretty", false, "indent JSON output for reading by hand",
+	)
+	timezone = flag.String(
+		"timezone", "UTC", "show dates and times in this zone, e.g. "+
+			"America/New_York",
+	)
+	tokensFile = flag.String(
+		"tokens-file", "", "file of tokens, one per line, to rotate between",
+	)
+	maxPages = flag.Int(
+		"max-pages", 100, "maximum number of pages of repos to list per org",
+	)
+	repoSort = flag.String(
+		"repo-sort", "pushed",
+		"list repos by created, updated, pushed or full_name",
+	)
+	recencyField = flag.String(
+		"recency-field", "pushed",
+		"keep repos recently pushed or updated (any change, not only commits)",
+	)
+	repoDirection = flag.String(
+		"repo-direction", "", "list repos asc or desc; GitHub picks by default",
+	)
+	sortBy = flag.String(
+		"sort-by", "commits", "order repos by commits, pushed or name",
+	)
+	top    = flag.Int("top", 0, "only print the top N repos")
+	sample = flag.Float64(
+		"sample", 0, "only scan this random fraction of repos, e.g. 0.1",
+	)
+	seed       = flag.Int64("seed", 0, "seed for --sample, to repeat a sample")
+	groupsFile = flag.String(
+		"groups-file", "", "JSON Lines file mapping repos to groups",
+	)
+	reposFile = flag.String(
+		"repos-file", "", "report on the owner/name repos listed in this file",
+	)
+	reposOutPath = flag.String(
+		"repos-out", "", "write the repos each org's stats are fetched for "+
+			"to this file, for --repos-file",
+	)
+	reposFromURL = flag.String(
+		"repos-from-url", "", "report on the owner/name repos listed at this URL",
+	)
+	metricName = flag.String(
+		"metric", "commits",
+		"what to report: commits, churn, contributors, merged-prs, score or "+
+			"punchcard",
+	)
+	weights = flag.String(
+		"weights", "commits=1,prs=1,issues=1",
+		"multipliers for --metric=score, e.g. commits=1,prs=2,issues=0.5",
+	)
+	branches = flag.String(
+		"branches", "default", "count commits on the default branch or all",
+	)
+	countBranch = flag.String(
+		"count-branch", "", "count commits on this branch instead of the default",
+	)
+	excludeBots = flag.Bool(
+		"exclude-bots", false,
+		"leave out commits by authors matching --bot-pattern",
+	)
+	botPattern = flag.String(
+		"bot-pattern", `\[bot\]$`,
+		"regexp of the logins --exclude-bots leaves out",
+	)
+	byMember = flag.Bool(
+		"by-member", false,
+		"rank authors by their commits across the org's repos instead",
+	)
+	topAuthors = flag.Int(
+		"top-authors", 0,
+		"like --by-member, but the top N authors with the repos they worked on",
+	)
+	colorMode = flag.String(
+		"color", "auto", "colorize the text summary: auto, always or never",
+	)
+	noColor = flag.Bool(
+		"no-color", false, "never colorize, the same as --color=never",
+	)
+	chart = flag.Bool(
+		"chart", false, "draw a bar per repo when printing to a terminal",
+	)
+	sparkline = flag.Bool(
+		"sparkline", false, "draw each repo's weekly commits, e.g. ▁▂▅█▃▁",
+	)
+	activeThreshold = flag.Int(
+		"active-threshold", 1, "commits a repo needs to count as active",
+	)
+	includeEmpty = flag.Bool(
+		"include-empty", false, "also list repos without activity",
+	)
+	includeEmptyRepos = flag.Bool(
+		"include-empty-repos", false,
+		"also list never-pushed and failed repos, labeled with their state",
+	)
+	strictEmpty = flag.Bool(
+		"strict-empty", false,
+		"list repos without any commits as empty instead of counting zero",
+	)
+	apiVersion = flag.String(
+		"api-version", envOr("GITHUB_API_VERSION", "2022-11-28"),
+		"GitHub REST API version to request",
+	)
+	outDir = flag.String(
+		"out-dir", "", "also write each org's report to {org}.json in this dir",
+	)
+	normalize = flag.Bool(
+		"normalize", false, "rank repos by commits per recent contributor",
+	)
+	showContributors = flag.Bool(
+		"contributors", false,
+		"also count each repo's authors with commits in the window",
+	)
+	cacheDir = flag.String(
+		"cache-dir", "", "cache responses here and revalidate them with ETags",
+	)
+	weight = flag.String(
+		"weight", "", "weigh commits to rank repos; size ranks by commits/KB",
+	)
+	sinceAuto = flag.Bool(
+		"since-auto", false, "start the window of new repos at their creation",
+	)
+	windowRange = flag.String(
+		"range", "", "count activity between two dates instead of the last "+
+			"six months, e.g. 2024-01-01..2024-06-30, or 2024-01-01.. until now",
+	)
+	sinceLastRun = flag.Bool(
+		"since-last-run", false, "count activity since the previous run",
+	)
+	stateFilePath = flag.String(
+		"state-file", "", "where --since-last-run records each run's time",
+	)
+	archivalCandidates = flag.Bool(
+		"archival-candidates", false,
+		"list repos last pushed 6 to 12 months ago with no recent commits",
+	)
+	dormant = flag.Bool(
+		"dormant", false,
+		"list every repo with no commits in the window, however recently pushed",
+	)
+	diffReports = flag.Bool(
+		"diff", false, "compare two saved JSON reports: --diff old.json new.json",
+	)
+	parallelOrgs = flag.Int(
+		"parallel-orgs", 1, "scan up to N orgs at once, sharing --concurrency",
+	)
+	concurrency = flag.Int(
+		"concurrency", 50, "how many repos to fetch statistics for at once",
+	)
+	requestTimeout = flag.Duration(
+		"request-timeout", 30*time.Second, "give up on a single request after",
+	)
+	statTimeout = flag.Duration(
+		"stat-timeout", 2*time.Minute, "stop polling a repo's stats after",
+	)
+	strictWindow = flag.Bool(
+		"strict-window", false, "only count weeks wholly inside the window",
+	)
+	outPath = flag.String(
+		"out", "", "write summaries to this file instead of stdout",
+	)
+	serve = flag.String(
+		"serve", "", "scan every --interval and serve the latest JSON report "+
+			"at this address, e.g. :8080",
+	)
+	interval = flag.Duration(
+		"interval", time.Hour, "with --serve, how often to scan again",
+	)
+	tee = flag.Bool(
+		"tee", false, "with --out, also write summaries to stdout",
+	)
+	budgetCheck = flag.Bool(
+		"check-budget", false,
+		"stop before fetching stats the rate limit left can't cover",
+	)
+	countRequests = flag.Bool(
+		"count-requests", false,
+		"list repos and print how many requests a scan takes, without one",
+	)
+	visibility = flag.String(
+		"visibility", "", "only report repos with these visibilities, e.g. "+
+			"public,internal",
+	)
+	createdAfter = flag.String(
+		"created-after", "", "only report repos created on or after this date, "+
+			"e.g. 2026-01-01",
+	)
+	createdBefore = flag.String(
+		"created-before", "", "only report repos created before this date",
+	)
+	webhook = flag.String(
+		"webhook", "", "POST each org's JSON report to this URL",
+	)
+	webhookHeader = flag.String(
+		"webhook-header", "", "extra \"Name: value\" header for --webhook",
+	)
+	includeOwner = flag.Bool(
+		"include-owner", false, "print repos as owner/name, even the org's own",
+	)
+	userAgent = flag.String(
+		"user-agent", "go-get-github-activity/"+version,
+		"User-Agent sent with every request",
+	)
+	maxRetries = flag.Int(
+		"max-retries", 10, "stop polling a repo's stats after this many retries",
+	)
+	verbose      = flag.Bool("verbose", false, "print extra detail per repo")
+	templateText = flag.String(
+		"template", "", "print each repo with this text/template, e.g. "+
+			"'{{.Name}} has {{.Commits}} commits'",
+	)
+	templateFile = flag.String(
+		"template-file", "", "like --template, but read from this file",
+	)
+	debug = flag.Bool(
+		"debug", false, "log each response's size and whether it was gzipped",
+	)
+	compact = flag.Bool(
+		"compact", false, "print a single line summary per org for CI logs",
+	)
+	quietSummary = flag.Bool(
+		"quiet-summary", false, "only print each org's total commits",
+	)
+)
+
+// createdFrom and createdUntil are --created-after and --created-before,
+// parsed by validateFlags; either is zero when unset
+var createdFrom, createdUntil time.Time
+
+// rangeStart and rangeEnd are --range, parsed by validateFlags; rangeEnd is
+// zero for an open range that runs until now
+var rangeStart, rangeEnd time.Time
+
+// zone is --timezone, loaded by validateFlags
+var zone = time.UTC
+
+// humanizeNumbers is --humanize-numbers and extraHeaders --header; one takes
+// an optional value and the other repeats, so they can't be declared with the
+// rest
+var (
+	humanizeNumbers numberFormat
+	extraHeaders    headerList
+)
+
+func init() {
+	flag.Var(
+		&humanizeNumbers, "humanize-numbers",
+		"print counts as 12,345 in the text output, or as 12.3k with =si",
+	)
+	flag.Var(
+		&extraHeaders, "header",
+		"add this \"Name: value\" header to every request to GitHub; repeatable",
+	)
+}
+
+// botRegexp is --bot-pattern, compiled by validateFlags
+var botRegexp *regexp.Regexp
+
+// envOr returns the environment variable, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
+
+// parseRange parses start..end dates, both YYYY-MM-DD, into the window from
+// the start of the first day to the end of the last. Without an end the window
+// runs until now and end is zero.
+func parseRange(s string) (start, end time.Time, err error) {
+	i := strings.Index(s, "..")
+	if i < 0 {
+		return start, end, fmt.Errorf("want start..end, got %q", s)
+	}
+
+	start, err = time.Parse("2006-01-02", s[:i])
+	if err != nil {
+		return start, end, err
+	}
+
+	last := time.Now().UTC()
+	if s[i+2:] != "" {
+		if last, err = time.Parse("2006-01-02", s[i+2:]); err != nil {
+			return start, end, err
+		}
+		end = last.AddDate(0, 0, 1)
+	}
+
+	if !start.Before(last) {
+		return start, end, fmt.Errorf("start must be before the end")
+	}
+	return start, end, nil
+}
+
+// validateFlags rejects flag values that would otherwise be silently ignored
+func validateFlags() error {
+	switch *format {
+	case "text", "json", "json-map", "html":
+	default:
+		return fmt.Errorf("unknown --format %q", *format)
+	}
+
+	switch *colorMode {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("unknown --color %q", *colorMode)
+	}
+
+	if *noColor && *colorMode == "always" {
+		return fmt.Errorf("--no-color and --color=always don't combine")
+	}
+
+	switch *repoSort {
+	case "created", "updated", "pushed", "full_name":
+	default:
+		return fmt.Errorf("unknown --repo-sort %q", *repoSort)
+	}
+
+	switch *recencyField {
+	case "pushed", "updated":
+	default:
+		return fmt.Errorf("unknown --recency-field %q", *recencyField)
+	}
+
+	switch *repoDirection {
+	case "", "asc", "desc":
+	default:
+		return fmt.Errorf("unknown --repo-direction %q", *repoDirection)
+	}
+
+	switch *sortBy {
+	case "commits", "pushed", "name":
+	default:
+		return fmt.Errorf("unknown --sort-by %q", *sortBy)
+	}
+
+	if _, ok := metrics[*metricName]; !ok {
+		return fmt.Errorf(
+			"unknown --metric %q, want one of %s", *metricName,
+			strings.Join(metricNames(), ", "),
+		)
+	}
+
+	if _, err := parseWeights(*weights); err != nil {
+		return err
+	}
+
+	if *metricName == "score" && *weight == "size" {
+		return fmt.Errorf("--weight=size doesn't combine with --metric=score")
+	}
+
+	templateFlag := "--template"
+	if *templateFile != "" {
+		if *templateText != "" {
+			return fmt.Errorf("--template and --template-file don't combine")
+		}
+
+		data, err := os.ReadFile(*templateFile)
+		if err != nil {
+			return fmt.Errorf("reading --template-file failed: %s", err)
+		}
+		// Each repo's line is ended already; don't double it
+		*templateText = strings.TrimSuffix(string(data), "\n")
+		templateFlag = "--template-file"
+	}
+
+	if _, err := template.New("repo").Parse(*templateText); err != nil {
+		return fmt.Errorf("parsing %s failed: %s", templateFlag, err)
+	}
+
+	if *parallelOrgs < 1 {
+		return fmt.Errorf("--parallel-orgs must be at least 1")
+	}
+
+	if *concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	if *maxRetries < 0 {
+		return fmt.Errorf("--max-retries can't be negative")
+	}
+
+	if *statTimeout <= 0 {
+		return fmt.Errorf("--stat-timeout must be positive")
+	}
+
+	if *sample < 0 || *sample > 1 {
+		return fmt.Errorf("--sample must be between 0 and 1")
+	}
+
+	if *weight != "" && *weight != "size" {
+		return fmt.Errorf("unknown --weight %q", *weight)
+	}
+
+	if *visibility != "" {
+		for _, v := range strings.Split(*visibility, ",") {
+			switch strings.TrimSpace(v) {
+			case "public", "private", "internal":
+			default:
+				return fmt.Errorf("unknown --visibility %q", v)
+			}
+		}
+	}
+
+	if *branches != "default" && *branches != "all" {
+		return fmt.Errorf("unknown --branches %q", *branches)
+	}
+
+	var err error
+	if botRegexp, err = regexp.Compile(*botPattern); err != nil {
+		return fmt.Errorf("parsing --bot-pattern failed: %s", err)
+	}
+
+	if *createdAfter != "" {
+		createdFrom, err = time.Parse("2006-01-02", *createdAfter)
+		if err != nil {
+			return fmt.Errorf("parsing --created-after failed: %s", err)
+		}
+	}
+
+	if *createdBefore != "" {
+		createdUntil, err = time.Parse("2006-01-02", *createdBefore)
+		if err != nil {
+			return fmt.Errorf("parsing --created-before failed: %s", err)
+		}
+	}
+
+	if !createdFrom.IsZero() && !createdUntil.IsZero() &&
+		!createdFrom.Before(createdUntil) {
+		return fmt.Errorf("--created-after must be before --created-before")
+	}
+
+	if zone, err = time.LoadLocation(*timezone); err != nil {
+		return fmt.Errorf("loading --timezone failed: %s", err)
+	}
+
+	if *topAuthors < 0 {
+		return fmt.Errorf("--top-authors must be positive")
+	}
+	if *topAuthors > 0 {
+		*byMember = true
+	}
+
+	if *windowRange != "" {
+		if rangeStart, rangeEnd, err = parseRange(*windowRange); err != nil {
+			return fmt.Errorf("parsing --range failed: %s", err)
+		}
+		if *sinceLastRun {
+			return fmt.Errorf("--range and --since-last-run don't combine")
+		}
+
+		// Counting from the commit activity statistics, which only cover the
+		// last 52 weeks, an older start would quietly undercount
+		fromStats := (*metricName == "commits" || *metricName == "score") &&
+			!*byMember && !*excludeBots && *countBranch == "" &&
+			*branches == "default"
+		oldest := time.Now().UTC().AddDate(0, 0, -52*7)
+		if fromStats && rangeStart.Before(oldest) {
+			return fmt.Errorf(
+				"--range can't start before %s, 52 weeks ago; GitHub's commit "+
+					"activity doesn't go back further",
+				oldest.Format("2006-01-02"),
+			)
+		}
+	}
+
+	if *excludeBots && (*countBranch != "" || *branches == "all") {
+		return fmt.Errorf("--exclude-bots only counts the default branch")
+	}
+
+	if *countBranch != "" && *branches == "all" {
+		return fmt.Errorf("--count-branch and --branches=all don't combine")
+	}
+
+	if *webhookHeader != "" {
+		if *webhook == "" {
+			return fmt.Errorf("--webhook-header needs --webhook")
+		}
+		if err := validWebhookHeader(*webhookHeader); err != nil {
+			return err
+		}
+	}
+
+	if *dormant && *archivalCandidates {
+		return fmt.Errorf("--dormant and --archival-candidates don't combine")
+	}
+
+	if *diffReports && flag.NArg() != 2 {
+		return fmt.Errorf("--diff needs two reports: --diff old.json new.json")
+	}
+
+	if *tee && *outPath == "" {
+		return fmt.Errorf("--tee needs --out")
+	}
+
+	if *serve != "" {
+		if *format != "text" && *format != "json" {
+			return fmt.Errorf("--serve only serves --format=json")
+		}
+		if *outPath != "" || *sinceLastRun {
+			return fmt.Errorf(
+				"--serve doesn't combine with --out or --since-last-run",
+			)
+		}
+		// Anything but the JSON of each org would garble the report served
+		if *quietSummary || *compact || *templateText != "" {
+			return fmt.Errorf(
+				"--serve doesn't combine with --quiet-summary, --compact, " +
+					"--template or --template-file",
+			)
+		}
+		// Only orgs are scanned again every --interval
+		if *groupsFile != "" || *reposFile != "" || *reposFromURL != "" {
+			return fmt.Errorf(
+				"--serve doesn't combine with --groups-file, --repos-file or " +
+					"--repos-from-url",
+			)
+		}
+		if flag.NArg() == 0 && *enterprise == "" {
+			return fmt.Errorf("--serve needs an org to scan")
+		}
+		if *interval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+type groupEntry struct {
+	Repo  string `json:"repo"`
+	Group string `json:"group"`
+}
+
+// readGroups parses a JSON Lines manifest of {"repo": ..., "group": ...}
+// records. Groups are returned in the order they first appear.
+func readGroups(r io.Reader) ([]string, map[string][]*repo, error) {
+	var order []string
+	groups := make(map[string][]*repo)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var entry groupEntry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, nil, fmt.Errorf(
+				"unmarshaling groups failed: %s on line %d", err, line,
+			)
+		}
+
+		if !strings.Contains(entry.Repo, "/") || entry.Group == "" {
+			return nil, nil, fmt.Errorf(
+				"groups entry on line %d needs an owner/name repo and a group",
+				line,
+			)
+		}
+
+		if _, ok := groups[entry.Group]; !ok {
+			order = append(order, entry.Group)
+		}
+		repos := groups[entry.Group]
+		groups[entry.Group] = append(repos, &repo{Name: entry.Repo})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return order, groups, nil
+}
+
+// GetMostActivityByGroup reports on the repos listed in a groups manifest,
+// skipping the org listing entirely and summarizing each group on its own.
+func GetMostActivityByGroup(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	order, groups, err := readGroups(f)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range order {
+		log.Printf("Getting statistics for each repo in group %s", group)
+
+		if err := reportOnRepos(group, groups[group]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportOnRepos fetches and prints statistics for a fixed set of repos, such
+// as a group, under the given name
+func reportOnRepos(name string, repos []*repo) error {
+	repos = sampleRepos(repos)
+	if *countRequests {
+		return printRequestCount(name, 0, repos)
+	}
+
+	if *budgetCheck {
+		if err := checkBudget(name, repos); err != nil {
+			return err
+		}
+		defer releaseBudget(repos)
+	}
+
+	reports, err := fetchStats(repos)
+
+	printMu.Lock()
+	var summary *orgSummary
+	var printErr error
+	switch {
+	case *byMember:
+		printErr = printMembers(name, reports)
+	case *metricName == "punchcard":
+		printErr = printPunchCard(name, reports)
+	default:
+		summary, printErr = printReport(name, reports)
+	}
+	printMu.Unlock()
+
+	if summary != nil && *webhook != "" {
+		postWebhook(*webhook, summary)
+	}
+	if printErr != nil {
+		return printErr
+	}
+
+	return err
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// headerList is --header, given once per header as "Name: value"
+type headerList []string
+
+func (h *headerList) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerList) Set(v string) error {
+	name, _, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%q is not \"Name: value\"", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// apply sets the headers on a request to GitHub. It runs after the token is
+// set, so an Authorization header given here replaces it.
+func (h headerList) apply(req *http.Request) {
+	for _, header := range h {
+		name, value, _ := strings.Cut(header, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+}
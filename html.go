@@ -0,0 +1,95 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// htmlReport is a standalone page, styles inline, so it opens anywhere
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Activity for {{.Org}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; text-align: left; }
+th { border-bottom: 2px solid #d0d7de; }
+td.count { text-align: right; }
+.bar { background: #2da44e; height: 12px; }
+</style>
+</head>
+<body>
+<h1>Activity for {{.Org}}</h1>
+<p>{{.Metric}} from {{.Start.Format "2006-01-02"}} to {{.End.Format "2006-01-02"}}
+{{- if .Sample}}, sampling {{printf "%.0f" .Sample}}% of repos{{end}}</p>
+{{- if .Rows}}
+<table>
+<tr><th>Repo</th><th>Count</th><th>Share</th><th></th></tr>
+{{- range .Rows}}
+<tr>
+<td>{{.Name}}</td>
+<td class="count">{{.Count}}</td>
+<td class="count">{{printf "%.0f" .Share}}%</td>
+<td><div class="bar" style="width: {{printf "%.0f" .Width}}px"></div></td>
+</tr>
+{{- end}}
+</table>
+<p>Active repos: {{.ActiveRepos}}</p>
+{{- else}}
+<p>No repositories with activity {{.Window}}.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlRow struct {
+	Name  string
+	Count int
+	Share float64
+	Width float64
+}
+
+type htmlPage struct {
+	Org         string
+	Metric      string
+	Start, End  time.Time
+	Window      string
+	Sample      float64
+	Rows        []htmlRow
+	ActiveRepos int
+}
+
+// writeHTML renders the summary as a page with a table of the sorted repos and
+// a bar each, scaled to the busiest repo
+func writeHTML(w io.Writer, summary *orgSummary) error {
+	start, end := window()
+
+	var highest int
+	for _, r := range summary.Repos {
+		if r.Summary > highest {
+			highest = r.Summary
+		}
+	}
+
+	page := &htmlPage{
+		Org: summary.Org, Metric: *metricName, Start: inZone(start),
+		End: inZone(end), Window: describeWindow(),
+		Sample: 100 * summary.Sample, ActiveRepos: summary.ActiveRepos,
+	}
+	for _, r := range summary.Repos {
+		row := htmlRow{
+			Name:  displayName(summary.Org, r.Name),
+			Count: r.Summary,
+			Share: r.Share,
+		}
+		if highest > 0 && r.Summary > 0 {
+			row.Width = 200 * float64(r.Summary) / float64(highest)
+		}
+		page.Rows = append(page.Rows, row)
+	}
+
+	return htmlReport.Execute(w, page)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberFormat is --humanize-numbers: given alone it groups thousands with
+// commas, and --humanize-numbers=si shortens counts to 12.3k instead
+type numberFormat string
+
+func (f *numberFormat) String() string { return string(*f) }
+
+func (f *numberFormat) Set(v string) error {
+	switch v {
+	case "true", "comma":
+		*f = "comma"
+	case "false", "":
+		*f = ""
+	case "si":
+		*f = "si"
+	default:
+		return fmt.Errorf("unknown number format %q, want comma or si", v)
+	}
+	return nil
+}
+
+// IsBoolFlag lets --humanize-numbers be given without a value
+func (f *numberFormat) IsBoolFlag() bool { return true }
+
+// humanize formats a count for the text output as --humanize-numbers asks
+func humanize(n int) string {
+	switch humanizeNumbers {
+	case "comma":
+		return withCommas(n)
+	case "si":
+		return withSI(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// withCommas groups the digits of n in threes, e.g. 12,345
+func withCommas(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
+
+// withSI shortens n to one decimal of the largest unit it reaches, e.g. 12.3k
+func withSI(n int) string {
+	value := float64(n)
+	if value > -1000 && value < 1000 {
+		return strconv.Itoa(n)
+	}
+
+	units := []string{"", "k", "M", "G"}
+	unit := 0
+	// Round before comparing so 999,950 becomes 1M rather than 1000.0k
+	for unit < len(units)-1 && (value >= 999.95 || value <= -999.95) {
+		value /= 1000
+		unit++
+	}
+
+	s := strconv.FormatFloat(value, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + units[unit]
+}
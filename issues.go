@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type issue struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Set when the issue is a pull request, which the issues listing includes
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// fetchOpenedIssues counts the issues opened within the window, leaving out
+// pull requests. Issues are listed newest first, so listing stops at the first
+// one opened before the window.
+func fetchOpenedIssues(r *repo) (int, error) {
+	start, end := window()
+
+	issuesURL := "https://api.github.com/repos/" + r.Name +
+		"/issues?state=all&sort=created&direction=desc&per_page=100"
+
+	var opened int
+	err := forEachPage(newClient(), issuesURL, func(dec *json.Decoder) error {
+		var page []*issue
+		if err := dec.Decode(&page); err != nil {
+			return err
+		}
+
+		for _, i := range page {
+			if i.CreatedAt.Before(start) {
+				return errStopPaging
+			}
+
+			if i.PullRequest == nil && !i.CreatedAt.After(end) {
+				opened++
+			}
+		}
+
+		return nil
+	})
+
+	return opened, err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// lastRun starts the window instead of six months ago under --since-last-run,
+// once a previous run has been recorded
+var lastRun time.Time
+
+// stateFile is where --since-last-run keeps the time of the last run, by
+// default in the user's cache directory. Each set of orgs and repo lists run
+// against gets a file of its own, so scanning one org doesn't move the window
+// of another.
+func stateFile() (string, error) {
+	if *stateFilePath != "" {
+		return *stateFilePath, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := "last-run-" + runKey()
+	return filepath.Join(dir, "go-get-github-activity", name), nil
+}
+
+// runKey names what a run scans, whatever order the orgs were given in
+func runKey() string {
+	var targets []string
+	for _, arg := range flag.Args() {
+		targets = append(targets, "org:"+strings.ToLower(arg))
+	}
+	if *enterprise != "" {
+		targets = append(targets, "enterprise:"+strings.ToLower(*enterprise))
+	}
+	if *groupsFile != "" {
+		targets = append(targets, "groups-file:"+*groupsFile)
+	}
+	if *reposFile != "" {
+		targets = append(targets, "repos-file:"+*reposFile)
+	}
+	if *reposFromURL != "" {
+		targets = append(targets, "repos-from-url:"+*reposFromURL)
+	}
+	sort.Strings(targets)
+
+	sum := sha256.Sum256([]byte(strings.Join(targets, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readLastRun returns the recorded time of the last run, or the zero time when
+// there hasn't been one yet
+func readLastRun(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+}
+
+// writeLastRun records when this run started, for the next one to pick up
+func writeLastRun(path string, t time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(t.Format(time.RFC3339)+"\n"), 0644)
+}
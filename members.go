@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+type memberCommits struct {
+	Login   string `json:"login"`
+	Commits int    `json:"commits"`
+
+	// Repos breaks the commits down by repo under --top-authors
+	Repos []*repoCommits `json:"repos,omitempty"`
+}
+
+type repoCommits struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+// fetchMemberCommits gets each author's commits to the repo within the window
+// for --by-member, from the contributors statistics
+func fetchMemberCommits(r *repo) *report {
+	contributors, tries, err := fetchContributorStats(newClient(), r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	sixMonthsAgo, now := window()
+
+	authors := make(map[string]int)
+	for _, c := range contributors {
+		login := c.login()
+		if login == "" || (*excludeBots && botRegexp.MatchString(login)) {
+			continue
+		}
+
+		if commits := c.commitsInWindow(sixMonthsAgo, now); commits > 0 {
+			authors[login] += commits
+		}
+	}
+
+	return &report{
+		Name: r.Name, PushedAt: r.PushedAt, Retries: tries, Authors: authors,
+	}
+}
+
+// printMembers adds up each author's commits across the org's repos and prints
+// them busiest first, along with the repos they were in under --top-authors
+func printMembers(org string, reports []*report) error {
+	byLogin := make(map[string]*memberCommits)
+	for _, r := range reports {
+		for login, commits := range r.Authors {
+			m, ok := byLogin[login]
+			if !ok {
+				m = &memberCommits{Login: login}
+				byLogin[login] = m
+			}
+
+			m.Commits += commits
+			if *topAuthors > 0 {
+				m.Repos = append(
+					m.Repos, &repoCommits{Name: r.Name, Commits: commits},
+				)
+			}
+		}
+	}
+
+	members := make([]*memberCommits, 0, len(byLogin))
+	for _, m := range byLogin {
+		members = append(members, m)
+
+		sort.Slice(m.Repos, func(i, j int) bool {
+			if m.Repos[i].Commits != m.Repos[j].Commits {
+				return m.Repos[i].Commits > m.Repos[j].Commits
+			}
+			return m.Repos[i].Name < m.Repos[j].Name
+		})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Commits != members[j].Commits {
+			return members[i].Commits > members[j].Commits
+		}
+		return members[i].Login < members[j].Login
+	})
+
+	if *topAuthors > 0 && len(members) > *topAuthors {
+		members = members[:*topAuthors]
+	}
+	if *top > 0 && len(members) > *top {
+		members = members[:*top]
+	}
+
+	if *format == "json" {
+		return newEncoder(stdout).Encode(map[string]interface{}{
+			"org":     org,
+			"members": members,
+		})
+	}
+
+	fmt.Fprintln(stdout, "\nMembers")
+	fmt.Fprintln(stdout, "-------")
+
+	for _, m := range members {
+		fmt.Fprintf(stdout, "%s: %s", m.Login, humanize(m.Commits))
+		for i, r := range m.Repos {
+			sep := ", "
+			if i == 0 {
+				sep = " ("
+			}
+			fmt.Fprintf(
+				stdout, "%s%s %s", sep, displayName(org, r.Name),
+				humanize(r.Commits),
+			)
+		}
+		if len(m.Repos) > 0 {
+			fmt.Fprint(stdout, ")")
+		}
+		fmt.Fprintln(stdout)
+	}
+
+	return nil
+}
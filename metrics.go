@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A metric fetches one repo's figure for the report, reported as its Summary
+type metric func(r *repo) *report
+
+// metrics are the values --metric accepts. Each runs concurrently per repo, so
+// adding one only takes a fetch function here.
+var metrics = map[string]metric{
+	"commits":      fetchCommits,
+	"churn":        fetchChurn,
+	"contributors": fetchContributorCount,
+	"merged-prs":   fetchMergedPulls,
+	"punchcard":    fetchPunchCard,
+	"score":        fetchScore,
+}
+
+func metricNames() []string {
+	var names []string
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetchCommits counts commits in the window, on the default branch unless
+// --branches=all or --count-branch, and leaving out bots under --exclude-bots
+func fetchCommits(r *repo) *report {
+	if *excludeBots {
+		return fetchHumanCommits(r)
+	}
+	if *countBranch != "" {
+		return fetchCommitsOn(newClient(), r, []string{*countBranch})
+	}
+	if *branches == "all" {
+		return fetchBranchCommits(r)
+	}
+	return fetchStat(r)
+}
+
+// fetchChurn adds up the lines added and deleted within the window
+func fetchChurn(r *repo) *report {
+	client := newClient()
+
+	url := "https://api.github.com/repos/" + r.Name + "/stats/code_frequency"
+
+	// Each entry is a [week, additions, deletions] triple; deletions are
+	// negative
+	var weeks [][3]int64
+	status, tries, err := pollStats(client, url, &weeks)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	var churn int64
+	if status == http.StatusOK {
+		sixMonthsAgo, now := window()
+		for _, w := range weeks {
+			if inWindow(w[0], sixMonthsAgo, now) {
+				churn += w[1] - w[2]
+			}
+		}
+	}
+
+	return &report{
+		Name: r.Name, Summary: int(churn), PushedAt: r.PushedAt, Retries: tries,
+	}
+}
+
+// fetchContributorCount counts the authors with commits within the window
+func fetchContributorCount(r *repo) *report {
+	count, err := fetchContributors(newClient(), r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	return &report{
+		Name: r.Name, Summary: count, Contributors: count, PushedAt: r.PushedAt,
+	}
+}
+
+// scoreWeights multiply each kind of activity in --metric=score
+type scoreWeights struct {
+	commits, prs, issues float64
+}
+
+// parseWeights reads --weights such as "commits=1,prs=2,issues=0.5"; any kind
+// left out weighs 1
+func parseWeights(s string) (scoreWeights, error) {
+	w := scoreWeights{commits: 1, prs: 1, issues: 1}
+	if s == "" {
+		return w, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return w, fmt.Errorf("--weights entry %q is not kind=weight", pair)
+		}
+
+		value, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil {
+			return w, fmt.Errorf("--weights entry %q: %s", pair, err)
+		}
+
+		switch kv[0] {
+		case "commits":
+			w.commits = value
+		case "prs":
+			w.prs = value
+		case "issues":
+			w.issues = value
+		default:
+			return w, fmt.Errorf(
+				"unknown --weights kind %q, want commits, prs or issues", kv[0],
+			)
+		}
+	}
+
+	return w, nil
+}
+
+// fetchScore combines commits, merged pull requests and opened issues in the
+// window into one figure, weighed by --weights. It takes the requests of all
+// three metrics.
+func fetchScore(r *repo) *report {
+	// Already checked by validateFlags
+	w, _ := parseWeights(*weights)
+
+	rep := fetchCommits(r)
+	if rep.Error != nil {
+		return rep
+	}
+
+	merged, err := countMergedPulls(r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	opened, err := fetchOpenedIssues(r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	rep.Score = w.commits*float64(rep.Summary) + w.prs*float64(merged) +
+		w.issues*float64(opened)
+	rep.Summary = int(math.Round(rep.Score))
+
+	return rep
+}
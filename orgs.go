@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+type org struct {
+	Login string `json:"login"`
+}
+
+// ListUserOrgs returns the login of every org the token can see
+func ListUserOrgs() ([]string, error) {
+	var logins []string
+	err := forEachPage(
+		newClient(), "https://api.github.com/user/orgs?per_page=100",
+		func(dec *json.Decoder) error {
+			var page []*org
+			err := dec.Decode(&page)
+			for _, o := range page {
+				logins = append(logins, o.Login)
+			}
+			return err
+		},
+	)
+	return logins, err
+}
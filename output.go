@@ -0,0 +1,418 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// stdout is where summaries are written; --out sends them to a file instead,
+// or to the file and stdout both under --tee. Progress logs stay on stderr.
+var stdout io.Writer = os.Stdout
+
+// openOut creates the --out file and points stdout at it
+func openOut(path string, tee bool) (*os.File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout = f
+	if tee {
+		stdout = io.MultiWriter(os.Stdout, f)
+	}
+
+	return f, nil
+}
+
+// writeText prints the summary block for an org or group of repos
+func writeText(w io.Writer, summary *orgSummary) error {
+	// An empty summary block looks like something broke; say so instead
+	if len(summary.Repos) == 0 {
+		fmt.Fprintf(
+			w, "\nNo repositories with activity %s for %s\n", describeWindow(),
+			summary.Org,
+		)
+	} else {
+		writeActive(w, summary)
+	}
+
+	if len(summary.Inactive) > 0 {
+		fmt.Fprintln(w, "\nInactive")
+		fmt.Fprintln(w, "--------")
+
+		for _, r := range summary.Inactive {
+			name := displayName(summary.Org, r.Name)
+			fmt.Fprintf(w, "%s: %s", name, humanize(r.Summary))
+			if *includeEmptyRepos || *strictEmpty {
+				fmt.Fprintf(w, " [%s]", r.State)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	return nil
+}
+
+// writeActive prints the summary block of active repos
+func writeActive(w io.Writer, summary *orgSummary) {
+	if summary.Sample > 0 {
+		fmt.Fprintf(
+			w, "\nSummary (sample of %.0f%% of repos)\n", 100*summary.Sample,
+		)
+	} else {
+		fmt.Fprintln(w, "\nSummary")
+	}
+	fmt.Fprintln(w, "-------")
+
+	var highest int
+	for _, r := range summary.Repos {
+		if r.Summary > highest {
+			highest = r.Summary
+		}
+	}
+
+	// Bars only make sense to a person looking at a terminal
+	drawChart := *chart && isTerminal(w)
+
+	var nameWidth int
+	for _, r := range summary.Repos {
+		if n := len(displayName(summary.Org, r.Name)); n > nameWidth {
+			nameWidth = n
+		}
+	}
+
+	colorize := useColor(w)
+	for _, r := range summary.Repos {
+		name := displayName(summary.Org, r.Name)
+		count := humanize(r.Summary)
+
+		bar := ""
+		if drawChart {
+			name = fmt.Sprintf("%-*s", nameWidth, name)
+			bar = chartBar(r.Summary, highest) + " "
+		}
+
+		if colorize {
+			name = paint(ansiBold+ansiCyan, name)
+			count = paint(countColor(r.Summary, highest), count)
+		}
+
+		if *sparkline && len(r.Weeks) > 0 {
+			count = sparklineOf(r.Weeks) + " " + count
+		}
+
+		if drawChart {
+			fmt.Fprintf(w, "%s  %s%s (%.0f%%)", name, bar, count, r.Share)
+		} else {
+			fmt.Fprintf(w, "%s: %s (%.0f%%)", name, count, r.Share)
+		}
+
+		if *normalize {
+			fmt.Fprintf(
+				w, " [%.1f per contributor, %d contributors]",
+				r.PerContributor, r.Contributors,
+			)
+		} else if *showContributors {
+			fmt.Fprintf(w, " [%d contributors]", r.Contributors)
+		}
+
+		if r.Since != nil {
+			fmt.Fprintf(
+				w, " [since %s]", inZone(*r.Since).Format("2006-01-02"),
+			)
+		}
+
+		if *weight == "size" {
+			fmt.Fprintf(w, " [%.3f commits/KB]", r.Score)
+		}
+
+		if *includeEmptyRepos {
+			fmt.Fprintf(w, " [%s]", r.State)
+		}
+
+		if *verbose {
+			fmt.Fprintf(w, " [%d retries]", r.Retries)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(
+		w, "-------\nActive repos: %s\n", humanize(summary.ActiveRepos),
+	)
+}
+
+// chartWidth is how many cells the bar of the busiest repo takes up
+const chartWidth = 40
+
+// chartBar draws count as a bar scaled against the highest count in the report
+func chartBar(count, highest int) string {
+	if highest <= 0 || count <= 0 {
+		return ""
+	}
+
+	cells := count * chartWidth / highest
+	if cells == 0 {
+		cells = 1
+	}
+	return strings.Repeat("\u2588", cells)
+}
+
+// sparkLevels are the block characters of a sparkline, lowest first
+var sparkLevels = []rune("\u2581\u2582\u2583\u2584\u2585\u2586\u2587\u2588")
+
+// sparklineOf draws weekly counts as a sparkline scaled to the busiest week
+func sparklineOf(weeks []int) string {
+	var highest int
+	for _, c := range weeks {
+		if c > highest {
+			highest = c
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range weeks {
+		level := 0
+		if highest > 0 {
+			level = c * (len(sparkLevels) - 1) / highest
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// writeCompact prints the org's key figures on one line for CI logs
+func writeCompact(w io.Writer, summary *orgSummary) error {
+	top := "none"
+	if len(summary.Repos) > 0 {
+		r := summary.Repos[0]
+		top = fmt.Sprintf("%s(%d)", displayName(summary.Org, r.Name), r.Summary)
+	}
+
+	_, err := fmt.Fprintf(
+		w, "%s: top=%s total=%d repos=%d", summary.Org, top, summary.Commits,
+		summary.ActiveRepos,
+	)
+	if err == nil && summary.Sample > 0 {
+		_, err = fmt.Fprintf(w, " sample=%g", summary.Sample)
+	}
+	if err == nil {
+		_, err = fmt.Fprintln(w)
+	}
+	return err
+}
+
+// templateRepo is what --template is executed with for each repo
+type templateRepo struct {
+	Name         string
+	Commits      int
+	Share        float64
+	PushedAt     time.Time
+	Contributors int
+}
+
+// writeTemplate prints a line per repo formatted by --template
+func writeTemplate(w io.Writer, text string, summary *orgSummary) error {
+	tmpl, err := template.New("repo").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range summary.Repos {
+		err := tmpl.Execute(w, &templateRepo{
+			Name:         displayName(summary.Org, r.Name),
+			Commits:      r.Summary,
+			Share:        r.Share,
+			PushedAt:     inZone(r.PushedAt),
+			Contributors: r.Contributors,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+// inZone is t as shown in --timezone, UTC by default; the window itself is
+// always worked out in UTC. A missing time is left as is.
+func inZone(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(zone)
+}
+
+// displayName drops the owner from a repo's full name when it is the org being
+// reported on; repos from anywhere else, or every repo under --include-owner,
+// keep their full name.
+func displayName(org, fullName string) string {
+	if *includeOwner {
+		return fullName
+	}
+
+	i := strings.Index(fullName, "/")
+	if i < 0 || !strings.EqualFold(fullName[:i], org) {
+		return fullName
+	}
+	return fullName[i+1:]
+}
+
+// jsonVersion is bumped whenever the JSON output changes incompatibly
+const jsonVersion = 1
+
+type jsonWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// jsonEnvelope wraps an org's repos with what consumers need to interpret them
+type jsonEnvelope struct {
+	Version     int        `json:"version"`
+	Org         string     `json:"org"`
+	Metric      string     `json:"metric"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Window      jsonWindow `json:"window"`
+	Sample      float64    `json:"sample,omitempty"`
+
+	// Requests counts every request of the run up to this report
+	Requests requestCounts `json:"requests"`
+
+	Repos []*report `json:"repos"`
+}
+
+// newEncoder writes JSON minified for piping, or indented by two spaces under
+// --json-pretty for reading
+func newEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if *jsonPretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+func writeJSON(w io.Writer, summary *orgSummary) error {
+	start, end := window()
+
+	reports := make([]*report, 0, len(summary.Repos)+len(summary.Inactive))
+	reports = append(reports, summary.Repos...)
+	reports = append(reports, summary.Inactive...)
+
+	for i, r := range reports {
+		zoned := *r
+		zoned.PushedAt = inZone(r.PushedAt)
+		if r.Since != nil {
+			since := inZone(*r.Since)
+			zoned.Since = &since
+		}
+		reports[i] = &zoned
+	}
+
+	return newEncoder(w).Encode(&jsonEnvelope{
+		Version:     jsonVersion,
+		Org:         summary.Org,
+		Metric:      *metricName,
+		GeneratedAt: inZone(time.Now()),
+		Window:      jsonWindow{Start: inZone(start), End: inZone(end)},
+		Sample:      summary.Sample,
+		Requests:    requests.snapshot(),
+		Repos:       reports,
+	})
+}
+
+// writeJSONMap writes the active repos as one object of full name to count,
+// for quick lookups with jq
+func writeJSONMap(w io.Writer, summary *orgSummary) error {
+	counts := make(map[string]int, len(summary.Repos))
+	for _, r := range summary.Repos {
+		counts[r.Name] = r.Summary
+	}
+	return newEncoder(w).Encode(counts)
+}
+
+// writeJSONFile saves an org's report as {org}.json inside dir
+func writeJSONFile(dir string, summary *orgSummary) error {
+	// An owner/name repo is written under a directory of its owner
+	path := filepath.Join(dir, filepath.FromSlash(summary.Org)+".json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSON(f, summary); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// printArchivalCandidates lists the repos without a commit in the last six
+// months, most recently pushed first
+func printArchivalCandidates(org string, reports []*report) error {
+	var candidates []*report
+	for _, r := range reports {
+		if r.Error == nil && r.Summary == 0 {
+			candidates = append(candidates, r)
+		}
+	}
+	sortReports(candidates, "pushed")
+
+	if *format == "json" {
+		return writeJSON(stdout, &orgSummary{Org: org, Repos: candidates})
+	}
+
+	fmt.Fprintln(stdout, "\nArchival candidates")
+	fmt.Fprintln(stdout, "-------------------")
+
+	for _, r := range candidates {
+		fmt.Fprintf(
+			stdout, "%s: last pushed %s\n", displayName(org, r.Name),
+			inZone(r.PushedAt).Format("2006-01-02"),
+		)
+	}
+
+	return nil
+}
+
+// printDormant lists the repos without a commit in the last six months,
+// however recently they were pushed, longest since their last push first
+func printDormant(org string, reports []*report) error {
+	var dormantRepos []*report
+	for _, r := range reports {
+		if r.Error == nil && r.Summary == 0 {
+			dormantRepos = append(dormantRepos, r)
+		}
+	}
+	sort.SliceStable(dormantRepos, func(i, j int) bool {
+		return dormantRepos[i].PushedAt.Before(dormantRepos[j].PushedAt)
+	})
+
+	if *format == "json" {
+		return writeJSON(stdout, &orgSummary{Org: org, Repos: dormantRepos})
+	}
+
+	fmt.Fprintln(stdout, "\nDormant repos")
+	fmt.Fprintln(stdout, "-------------")
+
+	for _, r := range dormantRepos {
+		pushed := "never pushed"
+		if !r.PushedAt.IsZero() {
+			pushed = "last pushed " + inZone(r.PushedAt).Format("2006-01-02")
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", displayName(org, r.Name), pushed)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// printMu keeps the summary of one org from interleaving with another's when
+// --parallel-orgs scans several at once; each prints whole while holding it
+var printMu sync.Mutex
+
+// scanOrgs reports on each org, or owner/name repo, up to --parallel-orgs at a
+// time, and returns the failures in the order the orgs were given. Summaries
+// print as each org finishes. After an interrupt no more orgs are started, and
+// errInterrupted is returned once the ones underway have printed what they
+// have.
+func scanOrgs(orgs []string) ([]string, error) {
+	if *parallelOrgs > 1 {
+		requestSlots = make(chan struct{}, *concurrency)
+	}
+
+	errs := make([]error, len(orgs))
+	sem := make(chan struct{}, *parallelOrgs)
+	var wg sync.WaitGroup
+	var interrupted int32
+	for i, org := range orgs {
+		sem <- struct{}{}
+		if atomic.LoadInt32(&interrupted) != 0 {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, org string) {
+			defer wg.Done()
+			errs[i] = scanOrg(org)
+			if errs[i] == errInterrupted {
+				atomic.StoreInt32(&interrupted, 1)
+			}
+			<-sem
+		}(i, org)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&interrupted) != 0 {
+		return nil, errInterrupted
+	}
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", orgs[i], err))
+		}
+	}
+	return failures, nil
+}
+
+// scanOrg reports on a single org, or an owner/name repo; an interrupt is left
+// for scanOrgs to stop on
+func scanOrg(org string) error {
+	var err error
+	if strings.Contains(org, "/") {
+		err = GetMostActivityForRepo(org)
+	} else {
+		err = GetMostActivityInSixMonths(org)
+	}
+	if err != nil && err != errInterrupted {
+		errorLog.Printf("Something went wrong: %v\n", err)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type pull struct {
+	MergedAt  *time.Time `json:"merged_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// fetchMergedPulls reports the pull requests merged within the window
+func fetchMergedPulls(r *repo) *report {
+	merged, err := countMergedPulls(r)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	return &report{Name: r.Name, Summary: merged, PushedAt: r.PushedAt}
+}
+
+// countMergedPulls counts the pull requests merged within the window. Closed
+// pulls are listed most recently updated first, and a pull is always updated
+// when it is merged, so listing stops at the first one older than the window.
+func countMergedPulls(r *repo) (int, error) {
+	start, end := window()
+
+	pullsURL := "https://api.github.com/repos/" + r.Name +
+		"/pulls?state=closed&sort=updated&direction=desc&per_page=100"
+
+	var merged int
+	err := forEachPage(newClient(), pullsURL, func(dec *json.Decoder) error {
+		var page []*pull
+		if err := dec.Decode(&page); err != nil {
+			return err
+		}
+
+		for _, p := range page {
+			if p.UpdatedAt.Before(start) {
+				return errStopPaging
+			}
+
+			if p.MergedAt != nil && p.MergedAt.After(start) &&
+				!p.MergedAt.After(end) {
+				merged++
+			}
+		}
+
+		return nil
+	})
+
+	return merged, err
+}
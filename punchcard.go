@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// punchCard holds commits by day of week (Sunday first) and hour of day
+type punchCard [7][24]int
+
+func fetchPunchCard(r *repo) *report {
+	client := newClient()
+
+	url := "https://api.github.com/repos/" + r.Name + "/stats/punch_card"
+
+	// Each entry is a [day, hour, commits] triple
+	var triples [][3]int
+	status, tries, err := pollStats(client, url, &triples)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	card := &punchCard{}
+	if status == http.StatusOK {
+		for _, t := range triples {
+			day, hour, commits := t[0], t[1], t[2]
+			if day >= 0 && day < 7 && hour >= 0 && hour < 24 {
+				card[day][hour] += commits
+			}
+		}
+	}
+
+	return &report{
+		Name: r.Name, PushedAt: r.PushedAt, Retries: tries, PunchCard: card,
+	}
+}
+
+// printPunchCard adds up the punch cards of every repo in the org
+func printPunchCard(org string, reports []*report) error {
+	var total punchCard
+	for _, r := range reports {
+		if r.PunchCard == nil {
+			continue
+		}
+		for day := range r.PunchCard {
+			for hour := range r.PunchCard[day] {
+				total[day][hour] += r.PunchCard[day][hour]
+			}
+		}
+	}
+
+	if *format == "json" {
+		return newEncoder(stdout).Encode(map[string]interface{}{
+			"org":        org,
+			"punch_card": total,
+		})
+	}
+
+	return writePunchCard(stdout, &total)
+}
+
+func writePunchCard(w io.Writer, card *punchCard) error {
+	days := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+	fmt.Fprintln(w, "\nPunch card")
+	fmt.Fprintln(w, "----------")
+
+	fmt.Fprint(w, "   ")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(w, "%5d", hour)
+	}
+	fmt.Fprintln(w)
+
+	for day, hours := range card {
+		fmt.Fprint(w, days[day])
+		for _, commits := range hours {
+			fmt.Fprintf(w, "%5d", commits)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 3+5*24))
+
+	return nil
+}
@@ -1,25 +1,42 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/peterhellberg/link"
 )
 
 type repo struct {
-	Name     string    `json:"full_name"`
-	PushedAt time.Time `json:"pushed_at"`
-	Error    error
+	Name      string    `json:"full_name"`
+	PushedAt  time.Time `json:"pushed_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Size      int       `json:"size"` // in KB
+
+	Description   string `json:"description"`
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+
+	// Visibility is public, private or internal, the last only on Enterprise
+	Visibility string `json:"visibility"`
+
+	Error error
 }
 
 type stat struct {
@@ -27,162 +44,794 @@ type stat struct {
 	Week  int64
 }
 
+// orgSummary is everything printed for an org, or any other set of repos
+type orgSummary struct {
+	Org         string
+	Commits     int
+	ActiveRepos int
+	Sample      float64 // fraction of repos scanned, when sampling
+	Repos       []*report
+	Inactive    []*report
+}
+
 type report struct {
-	Name    string
-	Summary int
-	Error   error
+	Name     string    `json:"name"`
+	Summary  int       `json:"commits"`
+	Share    float64   `json:"share"`
+	PushedAt time.Time `json:"pushed_at"`
+	Retries  int       `json:"retries,omitempty"`
+
+	Description   string `json:"description,omitempty"`
+	HTMLURL       string `json:"html_url,omitempty"`
+	DefaultBranch string `json:"default_branch,omitempty"`
+
+	// Since is set when the window was shortened to start at repo creation
+	Since *time.Time `json:"since,omitempty"`
+
+	Contributors   int     `json:"contributors,omitempty"`
+	PerContributor float64 `json:"per_contributor,omitempty"`
+
+	Score float64 `json:"score,omitempty"`
+
+	// State is empty (never any commits), inactive, active or error
+	State string `json:"state,omitempty"`
+
+	PunchCard *punchCard `json:"-"`
+
+	// Authors holds each login's commits under --by-member
+	Authors map[string]int `json:"-"`
+
+	// Weeks holds the commits of each week in the window, oldest first, for
+	// --sparkline; only the commit activity statistics have them
+	Weeks []int `json:"-"`
+
+	Error error `json:"-"`
 }
 
+// errorLog reports failures even when progress logging is silenced
+var errorLog = log.New(os.Stderr, "", 0)
+
 func init() {
 	log.SetFlags(0)
 }
 
 func main() {
-	for _, org := range os.Args[1:] {
-		if err := GetMostActivityInSixMonths(org); err != nil {
-			log.Printf("Something went wrong: %v\n", err)
+	flag.Parse()
+
+	if *quietSummary {
+		log.SetOutput(io.Discard)
+	}
+
+	if err := validateFlags(); err != nil {
+		errorLog.Fatalf("Something went wrong: %v\n", err)
+	}
+
+	if *outPath != "" {
+		f, err := openOut(*outPath, *tee)
+		if err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+		defer f.Close()
+	}
+
+	if *reposOutPath != "" {
+		f, err := os.Create(*reposOutPath)
+		if err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+		defer f.Close()
+		reposOut.f = f
+	}
+
+	// Diffing saved reports never touches the API
+	if *diffReports {
+		if err := DiffReports(flag.Arg(0), flag.Arg(1)); err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+		return
+	}
+
+	runStart := time.Now().UTC()
+	var lastRunFile string
+	if *sinceLastRun {
+		var err error
+		lastRunFile, err = stateFile()
+		if err == nil {
+			lastRun, err = readLastRun(lastRunFile)
+		}
+		if err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+
+		if lastRun.IsZero() {
+			log.Printf("No previous run recorded; counting the last six months")
+		} else {
+			log.Printf(
+				"Counting activity since the last run at %s",
+				inZone(lastRun).Format("2006-01-02 15:04 MST"),
+			)
+		}
+	}
+
+	pool, err := loadTokens(*tokensFile, *useGitCredential)
+	if err != nil {
+		errorLog.Fatalf("Something went wrong: %v\n", err)
+	}
+	tokens = pool
+
+	if *listOrgs {
+		logins, err := ListUserOrgs()
+		if err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+
+		for _, login := range logins {
+			fmt.Println(login)
+		}
+		return
+	}
+
+	orgs := flag.Args()
+	if *enterprise != "" {
+		log.Printf("Grabbing list of all orgs for enterprise %s", *enterprise)
+
+		enterpriseOrgs, err := ListEnterpriseOrgs(*enterprise)
+		if err != nil {
+			errorLog.Fatalf("Something went wrong: %v\n", err)
+		}
+
+		orgs = append(orgs, enterpriseOrgs...)
+	}
+
+	if *serve != "" {
+		err := serveReports(*serve, orgs)
+		errorLog.Fatalf("Something went wrong: %v\n", err)
+	}
+
+	if *groupsFile != "" {
+		err := GetMostActivityByGroup(*groupsFile)
+		if err == errInterrupted {
+			errorLog.Fatalf("Stopped: %v\n", err)
+		}
+		if err != nil {
+			errorLog.Printf("Something went wrong: %v\n", err)
+		}
+	}
+
+	if *reposFile != "" {
+		err := GetMostActivityFromFile(*reposFile)
+		if err == errInterrupted {
+			errorLog.Fatalf("Stopped: %v\n", err)
+		}
+		if err != nil {
+			errorLog.Printf("Something went wrong: %v\n", err)
+		}
+	}
+
+	if *reposFromURL != "" {
+		err := GetMostActivityFromURL(*reposFromURL)
+		if err == errInterrupted {
+			errorLog.Fatalf("Stopped: %v\n", err)
+		}
+		if err != nil {
+			errorLog.Printf("Something went wrong: %v\n", err)
+		}
+	}
+
+	failures, err := scanOrgs(orgs)
+	if err == errInterrupted {
+		errorLog.Fatalf("Stopped: %v\n", err)
+	}
+
+	// Only the text summary has room for a line after the last org
+	if *format == "text" && *templateText == "" && !*compact &&
+		!*quietSummary {
+		runTotal.write(stdout)
+	}
+
+	// Errors scroll by with the progress logs; sum them up once at the end
+	if len(orgs) > 1 {
+		outcome := fmt.Sprintf(
+			"Orgs: %d succeeded, %d failed", len(orgs)-len(failures),
+			len(failures),
+		)
+		if len(failures) > 0 {
+			outcome += " (" + strings.Join(failures, "; ") + ")"
+		}
+		log.Print(outcome)
+	}
+
+	// A failed org would miss the activity in between if the run counted
+	if *sinceLastRun && len(failures) == 0 {
+		if err := writeLastRun(lastRunFile, runStart); err != nil {
+			errorLog.Printf("Something went wrong: %v\n", err)
 		}
 	}
+
+	logStaleNote()
+
+	counts := requests.snapshot()
+	log.Printf(
+		"Requests: %d, %d retried, %d rate limited", counts.Total,
+		counts.Retries, counts.RateLimited,
+	)
+	log.Print(&rateLimit)
+}
+
+// listWorkers is how many pages of repos are fetched at once
+const listWorkers = 10
+
+// statsRequests is how many requests fetching the repos' stats takes at least:
+// one each, or two with --normalize or --contributors
+func statsRequests(repos []*repo) int {
+	perRepo := 1
+	if *normalize || *showContributors {
+		perRepo++
+	}
+	return len(repos) * perRepo
+}
+
+// printRequestCount prints how many requests a scan takes under
+// --count-requests: the listing pages already made plus a stats request per
+// repo, or two with --normalize or --contributors. Polling retries aren't
+// known in advance, and --branches=all and --count-branch list commits page by
+// page, so both cost more.
+func printRequestCount(org string, pages int, repos []*repo) error {
+	stats := statsRequests(repos)
+
+	printMu.Lock()
+	defer printMu.Unlock()
+
+	_, err := fmt.Fprintf(
+		stdout, "%s: %d listing pages + %d stats requests = %d requests\n",
+		org, pages, stats, pages+stats,
+	)
+	return err
+}
+
+// GetMostActivityForRepo reports on a single owner/name repo, skipping the org
+// listing entirely. The summary goes by the repo's full name, so several repos
+// of one owner each keep their own.
+func GetMostActivityForRepo(name string) error {
+	log.Printf("Getting statistics for %s", name)
+
+	return reportOnRepos(name, []*repo{{Name: name}})
 }
 
 func GetMostActivityInSixMonths(org string) error {
 	// 1. Get a list of all repos ordered by pushed_at
 	log.Printf("Grabbing list of all repos for %s", org)
 
-	client := &http.Client{}
+	client := newClient()
 
-	reposURL := "https://api.github.com/orgs/" + org + "/repos?sort=pushed"
+	// 100 repos a page, the most GitHub allows, so --max-pages covers 10,000
+	query := url.Values{}
+	query.Set("per_page", "100")
+	query.Set("sort", *repoSort)
+	if *repoDirection != "" {
+		query.Set("direction", *repoDirection)
+	}
+	reposURL := "https://api.github.com/orgs/" + org + "/repos?" +
+		query.Encode()
 
 	req, _ := http.NewRequest("GET", reposURL, nil)
-	req.SetBasicAuth(os.Getenv("GITHUB_USERNAME"), os.Getenv("GITHUB_TOKEN"))
 
-	resp, err := client.Do(req)
-	if err != nil {
+	var list []*repo
+	resp, err := getJSON(client, req, &list)
+	if resp == nil {
 		return err
 	}
 
-	defer resp.Body.Close()
+	if err := ssoError(org, resp); err != nil {
+		return err
+	}
+
+	if err := fineGrainedError(org, resp); err != nil {
+		return err
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("getting index failed: %s", resp.Status)
 	}
 
-	var list []*repo
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+	if err != nil {
 		return fmt.Errorf("unmarhaling index failed: %s", err)
 	}
 
-	var total int
-	for _, l := range link.Parse(resp.Header.Get("link")) {
-		if l.Rel == "last" {
-			lastURL, err := url.Parse(l.String())
-			if err != nil {
-				return fmt.Errorf("list all repos by org failed: %s", err)
-			}
+	// An org without repos has nothing to filter or fetch stats for, and an
+	// empty summary would look like something broke
+	if len(list) == 0 {
+		log.Printf("Org %s has no repositories", org)
+		return nil
+	}
+
+	// 2. Filter down list and keep anything pushed within the last six months;
+	// each page is filtered as it arrives so the rest are never held on to
+	log.Printf("Filtering list within six months of commit activity")
+
+	sixMonthsAgo, now := window()
 
-			total, _ = strconv.Atoi(lastURL.Query().Get("page"))
+	// created counts the repos within --created-after and --created-before,
+	// whatever their push date; pages are filtered one at a time
+	var created int
+
+	pushedRecently := func(item *repo) bool {
+		if item.Error != nil {
+			return false
 		}
-	}
 
-	// Grab additional repos only if pagination is available
-	if total > 0 {
-		pendingRepoURLs := make(chan string)
-		processedRepoURLs := make(chan []*repo, total-1) // have first item above
+		if !visible(item) {
+			return false
+		}
 
-		// Create a max set of workers that match the amount of pages available
-		for i := 2; i <= total; i++ {
-			go workerForRepos(pendingRepoURLs, processedRepoURLs)
+		if !createdInRange(item) {
+			return false
 		}
+		created++
 
-		// Queue all available repos that we need to process
-		for i := 2; i <= total; i++ {
-			nextReposURL := reposURL + "&page=" + strconv.Itoa(i)
-			pendingRepoURLs <- nextReposURL
+		// Dormant repos are found by their stats, whenever they were pushed
+		if *dormant {
+			return true
+		}
+
+		// Repos that were never pushed to have a null pushed_at
+		if item.PushedAt.IsZero() {
+			if *includeEmptyRepos || *strictEmpty {
+				return true
+			}
+			log.Printf("Skipping %s: empty repo", item.Name)
+			return false
+		}
+
+		last := lastActive(item)
+
+		// Archival candidates went quiet between six and twelve months ago
+		if *archivalCandidates {
+			return last.After(now.AddDate(0, -12, 0)) &&
+				!last.After(sixMonthsAgo)
 		}
 
-		// List will contain all repos ordered by pushed_at
+		return last.After(sixMonthsAgo)
+	}
+
+	filteredByPushDateRepos := filterRepos(list, pushedRecently)
+
+	total, err := lastPage(resp.Header.Get("link"))
+	if err != nil {
+		return fmt.Errorf("list all repos by org failed: %s", err)
+	}
+
+	// Guard against a huge or bogus last page spawning that many requests
+	if *maxPages > 0 && total > *maxPages {
+		log.Printf(
+			"Warning: %s has %d pages of repos; only fetching the first %d",
+			org, total, *maxPages,
+		)
+		total = *maxPages
+	}
+
+	// listed counts the repos on every page that came back; a page that still
+	// failed once getJSON gave up retrying is recorded instead
+	listed := len(list)
+	var failedPages []int
+
+	// Grab additional repos only if pagination is available; an org that fits
+	// on one page has no Link header, so total is zero and the first page
+	// filtered above is the whole list
+	if total > 1 {
+		processedRepoURLs := make(chan *repoPage)
+
+		// Hold a slot of the semaphore per page in flight, however many pages
+		// are available, so mega-orgs don't fetch them all at once; the slot
+		// is only given back once the page has been handed over
+		sem := make(chan struct{}, listWorkers)
+		go func() {
+			for i := 2; i <= total; i++ {
+				sem <- struct{}{}
+				go func(number int) {
+					pageURL := reposURL + "&page=" + strconv.Itoa(number)
+					processedRepoURLs <- &repoPage{number, fetchRepo(pageURL)}
+					<-sem
+				}(i)
+			}
+		}()
+
+		// List will contain all recently pushed repos
 		for i := 2; i <= total; i++ {
-			list = append(list, <-processedRepoURLs...)
+			page := <-processedRepoURLs
+			if err := page.err(); err != nil {
+				log.Printf(
+					"Warning: page %d of repos for %s failed: %s", page.number,
+					org, err,
+				)
+				failedPages = append(failedPages, page.number)
+				continue
+			}
+
+			listed += len(page.repos)
+			filteredByPushDateRepos = append(
+				filteredByPushDateRepos,
+				filterRepos(page.repos, pushedRecently)...,
+			)
 		}
 	}
 
-	// 2. Filter down list and keep anything pushed within the last six months
-	log.Printf("Filtering list within six months of commit activity")
+	// Every page but the last is full, so each failed one is missing as many
+	// repos as the first page held, at most
+	var listErr error
+	if len(failedPages) > 0 {
+		sort.Ints(failedPages)
+		pages := strings.Trim(fmt.Sprint(failedPages), "[]")
 
-	now := time.Now().UTC()
-	sixMonthsAgo := now.AddDate(0, -6, 0)
+		log.Printf(
+			"Warning: listed %d of up to %d repos for %s; missing pages %s",
+			listed, listed+len(failedPages)*len(list), org, pages,
+		)
+		listErr = fmt.Errorf(
+			"%d of %d pages of repos failed, missing pages %s", len(failedPages),
+			total, pages,
+		)
+	}
 
-	filteredByPushDateRepos := filterRepos(list, func(item *repo) bool {
-		return item.PushedAt.After(sixMonthsAgo)
-	})
+	if !createdFrom.IsZero() || !createdUntil.IsZero() {
+		log.Printf(
+			"%d repos created in range, %d of them recently active", created,
+			len(filteredByPushDateRepos),
+		)
+	}
+
+	filteredByPushDateRepos = sampleRepos(filteredByPushDateRepos)
+
+	// Pages arrive out of order; restore pushed_at ordering before keeping
+	// only the most recently pushed repos
+	if *reposLimit > 0 && len(filteredByPushDateRepos) > *reposLimit {
+		sort.Slice(filteredByPushDateRepos, func(i, j int) bool {
+			a, b := filteredByPushDateRepos[i], filteredByPushDateRepos[j]
+			return a.PushedAt.After(b.PushedAt)
+		})
+
+		log.Printf(
+			"Limiting stats to %d of %d repos", *reposLimit,
+			len(filteredByPushDateRepos),
+		)
+		filteredByPushDateRepos = filteredByPushDateRepos[:*reposLimit]
+	}
+
+	if reposOut.f != nil {
+		if err := writeRepoList(org, filteredByPushDateRepos); err != nil {
+			return err
+		}
+	}
+
+	if *countRequests {
+		// Without pagination there is no last page, only the one requested
+		pages := total
+		if pages < 1 {
+			pages = 1
+		}
+		err := printRequestCount(org, pages, filteredByPushDateRepos)
+		if err != nil {
+			return err
+		}
+		return listErr
+	}
+
+	if *budgetCheck {
+		if err := checkBudget(org, filteredByPushDateRepos); err != nil {
+			return err
+		}
+		defer releaseBudget(filteredByPushDateRepos)
+	}
 
 	// 3. Loop through each repo and get statistics for each project
 	log.Printf("Getting statistics for each repo from list")
 
-	pendingStatURLs := make(chan string)
-	processedStatURLs := make(chan *report, len(filteredByPushDateRepos))
+	reportByStats, err := fetchStats(filteredByPushDateRepos)
 
-	// Create a max set of workers that match the first set of workers
-	for i := 0; i < 50; i++ {
-		go workerForStats(pendingStatURLs, processedStatURLs)
+	printMu.Lock()
+	var summary *orgSummary
+	var printErr error
+	switch {
+	case *archivalCandidates:
+		printErr = printArchivalCandidates(org, reportByStats)
+	case *dormant:
+		printErr = printDormant(org, reportByStats)
+	case *byMember:
+		printErr = printMembers(org, reportByStats)
+	case *metricName == "punchcard":
+		printErr = printPunchCard(org, reportByStats)
+	default:
+		summary, printErr = printReport(org, reportByStats)
 	}
+	printMu.Unlock()
 
-	// Queue all available repos that we need stats for
-	for _, v := range filteredByPushDateRepos {
-		statsURL := "https://api.github.com/repos/"
-		nextStatsURL := statsURL + v.Name + "/stats/commit_activity"
+	// A slow webhook mustn't hold up the summaries of other orgs
+	if summary != nil && *webhook != "" {
+		postWebhook(*webhook, summary)
+	}
+	if printErr != nil {
+		return printErr
+	}
 
-		pendingStatURLs <- nextStatsURL
+	// The report is printed with whatever was listed, but a repo missing from
+	// it fails the org
+	if err == nil {
+		err = listErr
 	}
+	return err
+}
 
-	var reportByStats []*report
-	for i := 0; i < len(filteredByPushDateRepos); i++ {
-		reportByStats = append(reportByStats, <-processedStatURLs)
+// errInterrupted is returned alongside whatever results arrived before an
+// interrupt, so they can still be reported
+var errInterrupted = errors.New("interrupted; the report is partial")
+
+// linkRegexp matches a single <url>; rel="..." entry of a Link header
+var linkRegexp = regexp.MustCompile(`<([^>]*)>[^,<]*;\s*rel="?([^",]*)"?`)
+
+// lastPage returns the page number of the rel="last" link, or zero when there
+// is only one page. Headers the link package can't make sense of are scanned
+// for the entry directly rather than quietly treated as a single page.
+func lastPage(header string) (int, error) {
+	if header == "" {
+		return 0, nil
+	}
+
+	var last string
+	for _, l := range parseLink(header) {
+		if hasRel(l.Rel, "last") {
+			last = l.String()
+		}
+	}
+
+	if last == "" {
+		for _, m := range linkRegexp.FindAllStringSubmatch(header, -1) {
+			if hasRel(m[2], "last") {
+				last = m[1]
+			}
+		}
+	}
+
+	if last == "" {
+		return 0, nil
+	}
+
+	lastURL, err := url.Parse(last)
+	if err != nil {
+		return 0, err
+	}
+
+	page, err := strconv.Atoi(lastURL.Query().Get("page"))
+	if err != nil {
+		return 0, fmt.Errorf("bad last page in link %s", last)
 	}
 
-	// 4. Order report based on the number of commits over six months
-	sort.Slice(reportByStats, func(i, j int) bool {
-		return reportByStats[i].Summary < reportByStats[j].Summary
+	return page, nil
+}
+
+// parseLink guards against link.Parse panicking on values it doesn't expect,
+// such as an unquoted rel, treating the header as unparseable instead
+func parseLink(header string) (group link.Group) {
+	defer func() {
+		if recover() != nil {
+			group = nil
+		}
+	}()
+
+	return link.Parse(header)
+}
+
+// hasRel reports whether a space separated rel value includes want
+func hasRel(rel, want string) bool {
+	for _, r := range strings.Fields(rel) {
+		if strings.EqualFold(r, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleRepos picks a random --sample fraction of the repos, reproducibly when
+// a --seed is given
+func sampleRepos(repos []*repo) []*repo {
+	if *sample <= 0 || *sample >= 1 || len(repos) == 0 {
+		return repos
+	}
+
+	seed := *seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	n := int(math.Ceil(*sample * float64(len(repos))))
+	sampled := make([]*repo, len(repos))
+	copy(sampled, repos)
+
+	rand.New(rand.NewSource(seed)).Shuffle(len(sampled), func(i, j int) {
+		sampled[i], sampled[j] = sampled[j], sampled[i]
 	})
 
-	fmt.Println("\nSummary")
-	fmt.Println("-------")
+	log.Printf("Sampling %d of %d repos", n, len(repos))
+	return sampled[:n]
+}
+
+// fetchStats gets the commit activity of every repo, --concurrency at a time.
+// An interrupt stops it starting repos and waiting on the ones still being
+// polled, and returns the results that had already arrived.
+func fetchStats(repos []*repo) ([]*report, error) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	// Buffered for every repo, so workers left polling after an interrupt
+	// never block on handing over their result
+	processedStatRepos := make(chan *report, len(repos))
 
-	pattern, _ := regexp.Compile(org + "/(\\.?[a-zA-Z0-9].+)/stats")
-	for i := len(reportByStats) - 1; i >= 0; i-- {
-		summary := reportByStats[i].Summary
+	// Start a fetch for every repo, each holding a slot of the semaphore so
+	// no more than --concurrency run at once
+	sem := make(chan struct{}, *concurrency)
 
-		if summary > 0 {
-			name := pattern.FindStringSubmatch(reportByStats[i].Name)[1]
-			fmt.Printf("%s: %v\n", name, summary)
+	var reportByStats []*report
+	queued, interrupted := 0, false
+queue:
+	for _, v := range repos {
+		select {
+		case sem <- struct{}{}:
+			queued++
+		case <-interrupt:
+			interrupted = true
+			break queue
 		}
 
+		go func(r *repo) {
+			defer func() { <-sem }()
+			processedStatRepos <- statsFor(r)
+		}(v)
 	}
 
-	return nil
+	for i := 0; i < queued && !interrupted; i++ {
+		select {
+		case r := <-processedStatRepos:
+			reportByStats = append(reportByStats, r)
+		case <-interrupt:
+			interrupted = true
+		}
+	}
+
+	if !interrupted {
+		return reportByStats, nil
+	}
+
+	// Keep anything that finished while we were being interrupted
+	for {
+		select {
+		case r := <-processedStatRepos:
+			reportByStats = append(reportByStats, r)
+		default:
+			log.Printf(
+				"Interrupted with %d of %d repos done", len(reportByStats),
+				len(repos),
+			)
+			return reportByStats, errInterrupted
+		}
+	}
 }
 
-func workerForRepos(
-	pendingRepoURLs <-chan string, processedRepoURLs chan<- []*repo,
-) {
-	for pendingRepoURL := range pendingRepoURLs {
-		processedRepoURLs <- fetchRepo(pendingRepoURL)
+// printReport orders the reports for an org, or any other set of repos, and
+// prints the ones with activity. The summary is returned for --webhook, whose
+// POST is left until the print lock is released.
+func printReport(
+	org string, reportByStats []*report,
+) (*orgSummary, error) {
+	// 4. Order report based on the number of commits over six months, or
+	// however else was asked for
+	by := *sortBy
+	switch {
+	case *normalize:
+		by = "per-contributor"
+	case *weight == "size":
+		by = "score"
+	case *metricName == "score":
+		by = "score"
+	}
+	sortReports(reportByStats, by)
+
+	// Keep active repos and work out their share of the org's commits
+	summary := &orgSummary{Org: org}
+	if *sample > 0 && *sample < 1 {
+		summary.Sample = *sample
+	}
+	for _, r := range reportByStats {
+		summary.Commits += r.Summary
+		active := r.Summary > 0 && r.Summary >= *activeThreshold
+		if r.State == "" {
+			r.State = "inactive"
+			if active {
+				r.State = "active"
+			}
+		}
+
+		switch {
+		case active:
+			summary.Repos = append(summary.Repos, r)
+		case *includeEmptyRepos, *strictEmpty && r.State == "empty":
+			summary.Inactive = append(summary.Inactive, r)
+		case *includeEmpty && r.Error == nil:
+			summary.Inactive = append(summary.Inactive, r)
+		}
+	}
+	summary.ActiveRepos = len(summary.Repos)
+
+	for _, r := range summary.Repos {
+		r.Share = 100 * float64(r.Summary) / float64(summary.Commits)
+	}
+
+	runTotal.add(summary)
+
+	if *top > 0 && len(summary.Repos) > *top {
+		summary.Repos = summary.Repos[:*top]
+	}
+
+	if *outDir != "" {
+		if err := writeJSONFile(*outDir, summary); err != nil {
+			return nil, err
+		}
 	}
+
+	return summary, writeSummary(summary)
+}
+
+// writeSummary prints the summary to stdout in the format asked for
+func writeSummary(summary *orgSummary) error {
+	if *quietSummary {
+		_, err := fmt.Fprintf(stdout, "%s %d\n", summary.Org, summary.Commits)
+		return err
+	}
+
+	if *templateText != "" {
+		return writeTemplate(stdout, *templateText, summary)
+	}
+
+	if *compact {
+		return writeCompact(stdout, summary)
+	}
+
+	switch *format {
+	case "json":
+		return writeJSON(stdout, summary)
+	case "json-map":
+		return writeJSONMap(stdout, summary)
+	case "html":
+		return writeHTML(stdout, summary)
+	default:
+		return writeText(stdout, summary)
+	}
+}
+
+// repoPage is a page of an org's repos, fetched by fetchRepo
+type repoPage struct {
+	number int
+	repos  []*repo
+}
+
+// err is why the page couldn't be fetched, or nil
+func (p *repoPage) err() error {
+	if len(p.repos) == 1 && p.repos[0].Error != nil {
+		return p.repos[0].Error
+	}
+	return nil
 }
 
 func fetchRepo(url string) []*repo {
-	client := &http.Client{}
+	client := newClient()
 
 	req, _ := http.NewRequest("GET", url, nil)
-	req.SetBasicAuth(os.Getenv("GITHUB_USERNAME"), os.Getenv("GITHUB_TOKEN"))
 
-	resp, err := client.Do(req)
-	if err != nil {
+	var list []*repo
+	resp, err := getJSON(client, req, &list)
+	if resp == nil {
 		return []*repo{&repo{Error: err}}
 	}
 
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
 		return []*repo{
 			&repo{
@@ -193,8 +842,7 @@ func fetchRepo(url string) []*repo {
 		}
 	}
 
-	var list []*repo
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+	if err != nil {
 		return []*repo{
 			&repo{
 				Error: fmt.Errorf(
@@ -207,85 +855,319 @@ func fetchRepo(url string) []*repo {
 	return list
 }
 
-func workerForStats(
-	pendingStatURLs <-chan string, processedRepoURLs chan<- *report,
-) {
-	for pendingURL := range pendingStatURLs {
-		processedRepoURLs <- fetchStat(pendingURL)
+// statsFor fetches the --metric of a repo and rounds out its report
+func statsFor(r *repo) *report {
+	fetch := metrics[*metricName]
+	if *byMember {
+		fetch = fetchMemberCommits
+	}
+
+	processed := fetch(r)
+	// Names are reported in lower case, however the listing spelled them, so
+	// saved reports and repo lists match from run to run
+	processed.Name = strings.ToLower(r.Name)
+	if errors.Is(processed.Error, errOutOfScope) {
+		log.Printf("Skipping %s: %s", r.Name, processed.Error)
+		processed.State = "skipped"
+	} else if processed.Error != nil {
+		processed.State = "error"
+	}
+
+	// Carry over what the repo listing knows, for structured output
+	processed.Description = r.Description
+	processed.HTMLURL = r.HTMLURL
+	processed.DefaultBranch = r.DefaultBranch
+
+	if *normalize {
+		normalizeReport(r, processed)
+	} else if *showContributors {
+		countContributors(r, processed)
+	}
+
+	if *weight == "size" {
+		weighBySize(r, processed)
+	}
+
+	return processed
+}
+
+func fetchStat(r *repo) *report {
+	client := newClient()
+
+	url := "https://api.github.com/repos/" + r.Name + "/stats/commit_activity"
+
+	var stats []*stat
+	status, tries, err := pollStats(client, url, &stats)
+	if err != nil {
+		return &report{Name: r.Name, Error: err}
+	}
+
+	// Empty repository or no access to its statistics; default report
+	if status == http.StatusNoContent {
+		return &report{
+			Name: r.Name, PushedAt: r.PushedAt, Retries: tries, State: "empty",
+		}
+	}
+	if status != http.StatusOK {
+		return &report{Name: r.Name, PushedAt: r.PushedAt, Retries: tries}
+	}
+
+	// Only keep statistics from the last six months
+	sixMonthsAgo, now := window()
+
+	cutoff, since := windowStart(r, sixMonthsAgo)
+	if since != nil && !*strictWindow {
+		// Weeks start on Sunday; keep the one the repo was created in
+		cutoff = cutoff.AddDate(0, 0, -7)
+	}
+
+	for _, v := range stats {
+		observeWeek(v.Week)
+	}
+
+	filteredByWeekStats := filterStats(stats, func(item *stat) bool {
+		return inWindow(item.Week, cutoff, now)
+	})
+
+	var summary int
+	weeks := make([]int, 0, len(filteredByWeekStats))
+	for _, v := range filteredByWeekStats {
+		summary += v.Total
+		weeks = append(weeks, v.Total)
+	}
+
+	return &report{
+		Name: r.Name, Summary: summary, PushedAt: r.PushedAt, Retries: tries,
+		Since: since, Weeks: weeks,
+	}
+}
+
+// window returns the period activity is measured over: the last six months,
+// the dates of --range, or since the last run under --since-last-run
+func window() (start, end time.Time) {
+	end = time.Now().UTC()
+	if !rangeStart.IsZero() {
+		if !rangeEnd.IsZero() {
+			end = rangeEnd
+		}
+		return rangeStart, end
+	}
+	if !lastRun.IsZero() {
+		return lastRun, end
+	}
+	return end.AddDate(0, -6, 0), end
+}
+
+// describeWindow words the window for messages, such as "in the last six
+// months" or, under --range, "between 2024-01-01 and 2024-06-30"
+func describeWindow() string {
+	start, end := window()
+	switch {
+	case !rangeStart.IsZero() && !rangeEnd.IsZero():
+		// The end of --range is the day after the last one counted
+		return fmt.Sprintf(
+			"between %s and %s", start.Format("2006-01-02"),
+			end.AddDate(0, 0, -1).Format("2006-01-02"),
+		)
+	case !rangeStart.IsZero():
+		return "since " + start.Format("2006-01-02")
+	case !lastRun.IsZero():
+		return "since the last run at " +
+			inZone(start).Format("2006-01-02 15:04 MST")
+	}
+	return "in the last six months"
+}
+
+// inWindow reports whether the week of weekly statistics starting at the unix
+// time week counts towards a window. Weeks starting outside the window are left
+// out; under --strict-window so is a week running past its end, such as the
+// one still in progress.
+func inWindow(week int64, start, end time.Time) bool {
+	weekStart := time.Unix(week, 0).UTC()
+	if *strictWindow {
+		return !weekStart.Before(start) &&
+			!weekStart.AddDate(0, 0, 7).After(end)
 	}
+	return weekStart.After(start) && weekStart.Before(end)
 }
 
-func fetchStat(url string) *report {
-	client := &http.Client{}
+// windowStart returns when the repo's window begins. Under --since-auto, repos
+// created within the window start at their creation instead, which is also
+// returned so the report can say so.
+func windowStart(r *repo, start time.Time) (time.Time, *time.Time) {
+	if *sinceAuto && r.CreatedAt.After(start) {
+		created := r.CreatedAt.UTC()
+		return created, &created
+	}
+	return start, nil
+}
+
+// slowPoll is how long a repo's statistics are polled for before it is logged
+// as holding up a worker
+const slowPoll = 30 * time.Second
 
+// pollStats requests one of the statistics endpoints and decodes the result
+// into v. It returns the final status, which is only a success when there are
+// statistics to decode, and how many retries it took.
+func pollStats(
+	client *http.Client, url string, v interface{},
+) (int, int, error) {
 	req, _ := http.NewRequest("GET", url, nil)
-	req.SetBasicAuth(os.Getenv("GITHUB_USERNAME"), os.Getenv("GITHUB_TOKEN"))
 
-	// Grab statistic for repo within two minutes; use exponential back-off in
-	// order to support Github's background job which fires when compiling these
-	// statistics.
+	// Grab statistic for repo within two minutes (or --stat-timeout); use
+	// exponential back-off in order to support Github's background job which
+	// fires when compiling these statistics.
 	//
 	// Please see the following:
 	// https://developer.github.com/v3/repos/statistics/#a-word-about-caching
-	const timeout = 2 * time.Minute
+	timeout := *statTimeout
 	deadline := time.Now().Add(timeout)
 
-	for tries := 0; time.Now().Before(deadline); tries++ {
-		resp, err := client.Do(req)
-		if err != nil {
-			return &report{Error: err}
-		}
+	// A repo still being computed keeps its worker, and --concurrency slot,
+	// while it waits; say so once it has taken long enough to hold others up
+	started, warned := time.Now(), false
 
-		defer resp.Body.Close()
+	tries := 0
+	for ; time.Now().Before(deadline) && tries <= *maxRetries; tries++ {
+		if !warned && time.Since(started) > slowPoll {
+			warned = true
+			log.Printf(
+				"Still polling %s after %s; raise --concurrency if many repos "+
+					"are this slow", url, slowPoll,
+			)
+		}
 
-		// Statistics job has completed, send back the summarized results
-		if resp.StatusCode == http.StatusOK {
-			var stats []*stat
-			if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
-				return &report{
-					Error: fmt.Errorf(
-						"unmarshaling repo failed: %s for repo %s", err, url,
-					),
-				}
+		resp, err := doRequest(client, req)
+		if err != nil {
+			if !transientErr(err) {
+				return 0, tries, err
 			}
 
-			// Only keep statistics from the last six months
-			now := time.Now().UTC()
-			sixMonthsAgo := now.AddDate(0, -6, 0)
+			// The connection dropped under load; ask again like a 202
+			if tries < *maxRetries {
+				atomic.AddInt64(&requests.Retries, 1)
+				log.Printf("(%s); retrying request...", err)
+				time.Sleep(backoff(tries))
+			}
+			continue
+		}
 
-			filteredByWeekStats := filterStats(stats, func(item *stat) bool {
-				week := time.Unix(item.Week, 0).UTC()
-				return week.After(sixMonthsAgo)
-			})
+		switch resp.StatusCode {
+		// Statistics job has completed, send back the results
+		case http.StatusOK:
+			err := decodeBody(resp, v)
+			resp.Body.Close()
+			if err != nil {
+				return 0, tries, fmt.Errorf(
+					"unmarshaling repo failed: %s for repo %s", err, url,
+				)
+			}
+			return resp.StatusCode, tries, nil
 
-			var summary int
-			for _, v := range filteredByWeekStats {
-				summary += v.Total
+		// A fine-grained token that wasn't granted the repo; it is skipped
+		// rather than reported without commits
+		case http.StatusForbidden:
+			scoped := fineGrained(resp)
+			resp.Body.Close()
+			if scoped {
+				return 0, tries, errOutOfScope
 			}
+			return resp.StatusCode, tries, nil
+
+		// Empty repository with no content found, or server refuses to
+		// authorize request; there are no statistics to give
+		case http.StatusNoContent:
+			resp.Body.Close()
+			return resp.StatusCode, tries, nil
 
-			return &report{strings.ToLower(url), summary, nil}
+		// Statistics that GitHub won't compute, such as code frequency for
+		// repos with 10,000 or more commits
+		case http.StatusUnprocessableEntity:
+			resp.Body.Close()
+			return 0, tries, fmt.Errorf(
+				"statistics unavailable: %s for repo %s", resp.Status, url,
+			)
 		}
 
-		// Empty repository with no content found; default report
-		if resp.StatusCode == http.StatusNoContent {
-			return &report{url, 0, nil}
+		resp.Body.Close()
+
+		// Statistics job has not completed, submit the request again
+		if tries < *maxRetries {
+			atomic.AddInt64(&requests.Retries, 1)
+			log.Printf("(http %v); retrying request...", resp.StatusCode)
+			time.Sleep(backoff(tries))
 		}
+	}
+
+	if tries > *maxRetries {
+		return 0, tries, fmt.Errorf(
+			"server (%s) failed to respond after %d retries", url, *maxRetries,
+		)
+	}
+
+	return 0, tries, fmt.Errorf(
+		"server (%s) failed to respond after %s", url, timeout,
+	)
+}
 
-		// Server refuses to authorize request; default report
-		if resp.StatusCode == http.StatusForbidden {
-			return &report{url, 0, nil}
+// weighBySize scores the repo by commits per KB so small repos with a lot of
+// churn aren't drowned out by large monorepos.
+func weighBySize(r *repo, rep *report) {
+	size := r.Size
+	if size < 1 {
+		size = 1
+	}
+	rep.Score = float64(rep.Summary) / float64(size)
+}
+
+func sortReports(reports []*report, by string) {
+	sort.Slice(reports, func(i, j int) bool {
+		switch by {
+		case "pushed":
+			return reports[i].PushedAt.After(reports[j].PushedAt)
+		case "name":
+			return strings.ToLower(reports[i].Name) <
+				strings.ToLower(reports[j].Name)
+		case "per-contributor":
+			return reports[i].PerContributor > reports[j].PerContributor
+		case "score":
+			return reports[i].Score > reports[j].Score
 		}
+		return reports[i].Summary > reports[j].Summary
+	})
+}
 
-		// Statistics job has not completed, submit the request again
-		log.Printf("(http %v); retrying request...", resp.StatusCode)
-		time.Sleep(time.Second << uint(tries)) // exponential back-off
+// lastActive is when the repo was last pushed to or, under
+// --recency-field=updated, last changed in any way
+func lastActive(r *repo) time.Time {
+	if *recencyField == "updated" {
+		return r.UpdatedAt
 	}
+	return r.PushedAt
+}
 
-	return &report{
-		Error: fmt.Errorf(
-			"server (%s) failed to respond after %s", url, timeout,
-		),
+// visible reports whether the repo has one of the --visibility values, or any
+// visibility when the flag is unset
+func visible(r *repo) bool {
+	if *visibility == "" {
+		return true
+	}
+
+	for _, v := range strings.Split(*visibility, ",") {
+		if strings.TrimSpace(v) == r.Visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// createdInRange reports whether the repo was created within --created-after
+// and --created-before
+func createdInRange(r *repo) bool {
+	if !createdFrom.IsZero() && r.CreatedAt.Before(createdFrom) {
+		return false
 	}
+	return createdUntil.IsZero() || r.CreatedAt.Before(createdUntil)
 }
 
 func filterRepos(list []*repo, f func(*repo) bool) []*repo {
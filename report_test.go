@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverTransport sends every request, whatever its host, to the test server
+type serverTransport struct {
+	server *url.URL
+}
+
+func (t serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = t.server.Scheme, t.server.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// withServer points the shared client at a test server running handler, and
+// collects printed summaries in the returned buffer, until the test ends
+func withServer(t *testing.T, handler http.Handler) *bytes.Buffer {
+	server := httptest.NewServer(handler)
+	serverURL, _ := url.Parse(server.URL)
+
+	client := newClient()
+	client.Transport = serverTransport{serverURL}
+
+	var buf bytes.Buffer
+	out := stdout
+	stdout = &buf
+
+	t.Cleanup(func() {
+		client.Transport = nil
+		stdout = out
+		server.Close()
+	})
+	return &buf
+}
+
+// withTemplate prints each repo as --template=text until the test ends
+func withTemplate(t *testing.T, text string) {
+	old := *templateText
+	*templateText = text
+	t.Cleanup(func() { *templateText = old })
+}
+
+func TestSinglePageListing(t *testing.T) {
+	now := time.Now().UTC()
+	pushed := now.AddDate(0, -1, 0).Format(time.RFC3339)
+	week := now.AddDate(0, 0, -14).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/x/repos", func(w http.ResponseWriter, r *http.Request) {
+		// A single page has no Link header
+		if r.URL.Query().Get("page") != "" {
+			t.Errorf("requested page %s of a single page listing", r.URL)
+		}
+		fmt.Fprintf(
+			w, `[{"full_name":"x/a","pushed_at":%q},`+
+				`{"full_name":"x/b","pushed_at":%q}]`, pushed, pushed,
+		)
+	})
+	mux.HandleFunc("/repos/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/stats/commit_activity") {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `[{"total":7,"week":%d}]`, week)
+	})
+
+	buf := withServer(t, mux)
+	withTemplate(t, "{{.Name}} {{.Commits}}")
+
+	if err := GetMostActivityInSixMonths("x"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := map[string]bool{"a 7": true, "b 7": true}
+	if len(lines) != len(want) {
+		t.Fatalf("got summary %q, want a line for each repo", lines)
+	}
+	for _, line := range lines {
+		if !want[line] {
+			t.Errorf("unexpected summary line %q", line)
+		}
+	}
+}
+
+func TestNullPushedAt(t *testing.T) {
+	var list []*repo
+	err := json.Unmarshal([]byte(`[{"full_name":"a/b","pushed_at":null}]`), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !list[0].PushedAt.IsZero() {
+		t.Fatalf("got pushed_at %s for null, want zero", list[0].PushedAt)
+	}
+
+	pushed := time.Now().UTC().AddDate(0, -1, 0).Format(time.RFC3339)
+	week := time.Now().UTC().AddDate(0, 0, -14).Unix()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/a/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(
+			w, `[{"full_name":"a/b","pushed_at":null},`+
+				`{"full_name":"a/c","pushed_at":%q}]`, pushed,
+		)
+	})
+	mux.HandleFunc("/repos/", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/repos/a/b/") {
+			t.Errorf("fetched statistics of the never pushed a/b")
+		}
+		fmt.Fprintf(w, `[{"total":3,"week":%d}]`, week)
+	})
+
+	buf := withServer(t, mux)
+	withTemplate(t, "{{.Name}} {{.Commits}}")
+
+	if err := GetMostActivityInSixMonths("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.TrimSpace(buf.String()); got != "c 3" {
+		t.Errorf("got summary %q, want only c 3", got)
+	}
+}
+
+func TestLastPage(t *testing.T) {
+	const repos = "https://api.github.com/organizations/1/repos"
+
+	tests := []struct {
+		name    string
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{name: "single page", header: "", want: 0},
+		{
+			name: "multiple rels",
+			header: `<` + repos + `?page=2>; rel="next", ` +
+				`<` + repos + `?page=34>; rel="last", ` +
+				`<` + repos + `?page=1>; rel="first"`,
+			want: 34,
+		},
+		{
+			name: "quoted characters in the url",
+			header: `<` + repos + `?q=%22a%2Cb%22&page=3>; rel="next", ` +
+				`<` + repos + `?q=%22a%2Cb%22&page=9>; rel="last"`,
+			want: 9,
+		},
+		{
+			name: "page and per_page",
+			header: `<` + repos + `?per_page=100&page=2>; rel="next", ` +
+				`<` + repos + `?per_page=100&page=12>; rel="last"`,
+			want: 12,
+		},
+		{
+			name:   "space separated rel",
+			header: `<` + repos + `?page=5>; rel="next last"`,
+			want:   5,
+		},
+		{
+			name: "unquoted rel, which the link package panics on",
+			header: `<` + repos + `?page=2>; rel=next, ` +
+				`<` + repos + `?page=8>; rel=last`,
+			want: 8,
+		},
+		{
+			name:   "no last page",
+			header: `<` + repos + `?page=1>; rel="prev"`,
+			want:   0,
+		},
+		{
+			name:    "non-numeric page",
+			header:  `<` + repos + `?page=last>; rel="last"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lastPage(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lastPage(%q) error = %v, want error %v", tt.header,
+					err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lastPage(%q) = %d, want %d", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// githubHosts are the only hosts a repo list is fetched from with the token
+// and any --header
+var githubHosts = map[string]bool{
+	"api.github.com":            true,
+	"raw.githubusercontent.com": true,
+}
+
+// fetchRepoList reads a newline-delimited list of owner/name repos from url,
+// skipping blank lines and # comments. On GitHub the request is authorized
+// like any other, so the list can live in a private repo; anywhere else it is
+// sent without credentials.
+func fetchRepoList(url string) ([]*repo, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repo list failed: %s", err)
+	}
+
+	var resp *http.Response
+	if githubHosts[strings.ToLower(req.URL.Hostname())] {
+		// The contents API serves the raw file rather than JSON when asked
+		req.Header.Set("Accept", "application/vnd.github.raw")
+
+		resp, err = doWithRetry(newClient(), req)
+	} else {
+		log.Printf("Fetching %s without the token, as it isn't GitHub", url)
+
+		req.Header.Set("User-Agent", *userAgent)
+		resp, err = newClient().Do(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"fetching repo list failed: %s for %s", resp.Status, url,
+		)
+	}
+
+	return parseRepoList(resp.Body)
+}
+
+// parseRepoList reads a newline-delimited list of owner/name repos, skipping
+// blank lines and # comments
+func parseRepoList(r io.Reader) ([]*repo, error) {
+	var repos []*repo
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if !strings.Contains(text, "/") {
+			return nil, fmt.Errorf(
+				"repo list entry on line %d needs an owner/name repo", line,
+			)
+		}
+		repos = append(repos, &repo{Name: text})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// GetMostActivityFromURL reports on exactly the repos listed at url
+func GetMostActivityFromURL(url string) error {
+	log.Printf("Grabbing list of repos from %s", url)
+
+	repos, err := fetchRepoList(url)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Getting statistics for each repo from list")
+
+	return reportOnRepos(listName(url), repos)
+}
+
+// GetMostActivityFromFile reports on exactly the repos listed in a local file,
+// such as one written by --repos-out
+func GetMostActivityFromFile(path string) error {
+	log.Printf("Reading list of repos from %s", path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	repos, err := parseRepoList(f)
+	if err != nil {
+		return fmt.Errorf("reading %s failed: %s", path, err)
+	}
+
+	log.Printf("Getting statistics for each repo from list")
+
+	return reportOnRepos(listName(path), repos)
+}
+
+// reposOut is the --repos-out file every org's filtered repos are added to
+var reposOut struct {
+	sync.Mutex
+	f *os.File
+}
+
+// writeRepoList adds the repos an org's stats are fetched for to --repos-out,
+// under a comment naming the org, in the format --repos-file reads
+func writeRepoList(org string, repos []*repo) error {
+	reposOut.Lock()
+	defer reposOut.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", org)
+	for _, r := range repos {
+		fmt.Fprintln(&b, strings.ToLower(r.Name))
+	}
+
+	if _, err := io.WriteString(reposOut.f, b.String()); err != nil {
+		return fmt.Errorf("writing --repos-out failed: %s", err)
+	}
+	return nil
+}
+
+// listName names the report after the list's file, dropping any extension, so
+// it also works as the file name under --out-dir
+func listName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "repos"
+	}
+
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if name == "" || name == "." || name == "/" {
+		return "repos"
+	}
+	return name
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// listRetries bounds how many times a transient failure is retried
+const listRetries = 5
+
+// requestCounts instruments a run; the workers update it concurrently, so
+// fields are only accessed through sync/atomic
+type requestCounts struct {
+	Total       int64 `json:"total"`
+	Retries     int64 `json:"retries"`
+	RateLimited int64 `json:"rate_limited"`
+}
+
+var requests requestCounts
+
+// snapshot reads the counts so far
+func (c *requestCounts) snapshot() requestCounts {
+	return requestCounts{
+		Total:       atomic.LoadInt64(&c.Total),
+		Retries:     atomic.LoadInt64(&c.Retries),
+		RateLimited: atomic.LoadInt64(&c.RateLimited),
+	}
+}
+
+// maxBackoff caps a single wait between retries
+const maxBackoff = 30 * time.Second
+
+// backoff doubles the wait with every try, starting at a second, up to
+// maxBackoff; the shift alone would overflow after enough tries.
+func backoff(tries int) time.Duration {
+	wait := time.Second
+	for i := 0; i < tries && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// retryWait is how long to wait before retrying resp: as long as its
+// Retry-After asks, up to maxBackoff, or the usual back-off when it has none
+// or it is garbled
+func retryWait(resp *http.Response, tries int) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return backoff(tries)
+	}
+	// Compare in seconds; a huge value would overflow as a time.Duration
+	return time.Duration(min(seconds, int(maxBackoff/time.Second))) *
+		time.Second
+}
+
+// transient reports whether a status is likely to succeed if asked again
+func transient(status int) bool {
+	return status == http.StatusAccepted ||
+		status == http.StatusTooManyRequests ||
+		status >= http.StatusInternalServerError
+}
+
+// transientErr reports whether a transport error is likely to go away if the
+// request is sent again: the server closing the connection under load, with
+// an HTTP/2 GOAWAY, a reset or an early EOF.
+func transientErr(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	// net/http doesn't export its HTTP/2 GOAWAY error
+	return strings.Contains(err.Error(), "GOAWAY")
+}
+
+// doWithRetry sends the request, retrying transient statuses and transport
+// errors with exponential back-off (or as long as Retry-After asks, at most
+// maxBackoff) up to listRetries times. The last response is returned as is for
+// the caller to report.
+func doWithRetry(
+	client *http.Client, req *http.Request,
+) (*http.Response, error) {
+	for tries := 0; ; tries++ {
+		resp, err := doRequest(client, req)
+		if err != nil {
+			if !transientErr(err) || tries == listRetries {
+				return nil, err
+			}
+
+			atomic.AddInt64(&requests.Retries, 1)
+			log.Printf("(%s); retrying request...", err)
+			time.Sleep(backoff(tries))
+			continue
+		}
+
+		if !transient(resp.StatusCode) || tries == listRetries {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+
+		wait := retryWait(resp, tries)
+
+		atomic.AddInt64(&requests.Retries, 1)
+		log.Printf("(http %v); retrying request...", resp.StatusCode)
+		time.Sleep(wait)
+	}
+}
+
+// getJSON sends the request with doWithRetry and decodes a 200 response into
+// v. A body cut short mid-stream, by a reset connection, is asked for again
+// rather than failing like JSON of the wrong shape would. Other statuses are
+// returned undecoded for the caller to report, with their body read into
+// memory. The body is always closed, and a response is only returned along
+// with an error when decoding failed.
+func getJSON(
+	client *http.Client, req *http.Request, v interface{},
+) (*http.Response, error) {
+	for tries := 0; ; tries++ {
+		resp, err := doWithRetry(client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the body, which explains most errors, for the caller to report
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		}
+
+		err = decodeBody(resp, v)
+		resp.Body.Close()
+		if err == nil || !transientErr(err) || tries == listRetries {
+			return resp, err
+		}
+
+		atomic.AddInt64(&requests.Retries, 1)
+		log.Printf("(%s); retrying request...", err)
+		time.Sleep(backoff(tries))
+	}
+}
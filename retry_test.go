@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		tries int
+		want  time.Duration
+	}{
+		{0, time.Second},
+		{5, maxBackoff},
+		{1000, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.tries); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.tries, got, tt.want)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	tests := []struct {
+		retryAfter string
+		want       time.Duration
+	}{
+		{"", backoff(2)},
+		{"0", 0},
+		{"10", 10 * time.Second},
+		{"3600", maxBackoff},
+		{"3601", maxBackoff},
+		{"99999999999999999", maxBackoff},
+		{"-1", backoff(2)},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", backoff(2)},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.retryAfter)
+
+		if got := retryWait(resp, 2); got != tt.want {
+			t.Errorf("retryWait(Retry-After: %q) = %s, want %s", tt.retryAfter,
+				got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// neededScopes are the classic token scopes a full report relies on, each with
+// the broader scopes that also grant it and what goes missing without it
+var neededScopes = []struct {
+	scope   string
+	grantBy []string
+	missing string
+}{
+	{"repo", nil, "private repos won't be counted"},
+	{
+		"read:org", []string{"write:org", "admin:org"},
+		"org repos and members may be left out",
+	},
+}
+
+var scopesChecked sync.Once
+
+// checkScopes warns, once per run, when the token is missing scopes the report
+// needs. Only classic tokens send X-OAuth-Scopes; fine-grained tokens and apps
+// are scoped per repo and aren't checked.
+func checkScopes(resp *http.Response) {
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return
+	}
+
+	scopesChecked.Do(func() {
+		granted := make(map[string]bool)
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			granted[strings.TrimSpace(scope)] = true
+		}
+
+		for _, needed := range neededScopes {
+			has := granted[needed.scope]
+			for _, broader := range needed.grantBy {
+				has = has || granted[broader]
+			}
+
+			if !has {
+				log.Printf(
+					"Warning: token is missing the %s scope; %s", needed.scope,
+					needed.missing,
+				)
+			}
+		}
+
+		accepted := resp.Header.Get("X-Accepted-OAuth-Scopes")
+		if accepted == "" {
+			return
+		}
+		for _, scope := range strings.Split(accepted, ",") {
+			if granted[strings.TrimSpace(scope)] {
+				return
+			}
+		}
+		log.Printf(
+			"Warning: %s accepts the scopes %s, which the token has none of",
+			resp.Request.URL.Path, accepted,
+		)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latestReport is the JSON of the last scan under --serve: an array of every
+// org's object as --format=json prints it
+type latestReport struct {
+	mu        sync.Mutex
+	body      []byte
+	scannedAt time.Time
+}
+
+// scan reports on the orgs into a buffer and keeps it as the latest report,
+// unless every org failed and there is nothing new to show
+func (l *latestReport) scan(orgs []string) {
+	var buf bytes.Buffer
+	stdout = &buf
+
+	failures, err := scanOrgs(orgs)
+	if err == errInterrupted {
+		errorLog.Fatalf("Stopped: %v\n", err)
+	}
+	if len(failures) > 0 {
+		log.Printf(
+			"Scan: %d of %d orgs failed (%s)", len(failures), len(orgs),
+			strings.Join(failures, "; "),
+		)
+	}
+	if len(failures) == len(orgs) {
+		return
+	}
+
+	body, err := jsonArray(&buf)
+	if err != nil {
+		errorLog.Printf("Something went wrong: %v\n", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.body, l.scannedAt = body, time.Now()
+}
+
+// jsonArray gathers the objects printed one after another for each org into a
+// single JSON array, so the report is one document
+func jsonArray(r io.Reader) ([]byte, error) {
+	envelopes := []json.RawMessage{}
+	dec := json.NewDecoder(r)
+	for {
+		var envelope json.RawMessage
+		err := dec.Decode(&envelope)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("collecting the report failed: %s", err)
+		}
+		envelopes = append(envelopes, envelope)
+	}
+
+	var buf bytes.Buffer
+	if err := newEncoder(&buf).Encode(envelopes); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (l *latestReport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	body, scannedAt := l.body, l.scannedAt
+	l.mu.Unlock()
+
+	if body == nil {
+		http.Error(w, "no report yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeContent(w, r, "", scannedAt, bytes.NewReader(body))
+}
+
+// serveReports scans the orgs every --interval and serves the latest report
+// at /report, with /healthz for liveness checks. Other than a failing
+// listener, it only returns when the process is stopped.
+func serveReports(addr string, orgs []string) error {
+	*format = "json"
+
+	latest := &latestReport{}
+	go func() {
+		for {
+			latest.scan(orgs)
+			log.Printf("Next scan in %s", *interval)
+			time.Sleep(*interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/report", latest)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+
+	log.Printf("Serving the latest report at http://%s/report", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// newestWeek is the start of the most recent weekly bucket in any repo's
+// stats, as a Unix time, or zero before any were fetched
+var newestWeek int64
+
+// observeWeek records the week when it is the newest seen so far
+func observeWeek(week int64) {
+	for {
+		newest := atomic.LoadInt64(&newestWeek)
+		if week <= newest ||
+			atomic.CompareAndSwapInt64(&newestWeek, newest, week) {
+			return
+		}
+	}
+}
+
+// logStaleNote reminds that stats come from GitHub's cached computation, which
+// can trail a push by some minutes, so a commit pushed just before the run may
+// not be counted yet
+func logStaleNote() {
+	week := atomic.LoadInt64(&newestWeek)
+	if week == 0 {
+		return
+	}
+
+	log.Printf(
+		"Note: commit stats are computed and cached by GitHub and may trail "+
+			"recent pushes; the newest week in them starts %s",
+		time.Unix(week, 0).UTC().Format("2006-01-02"),
+	)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// grandTotal adds up every org reported on in a run, for the line printed
+// after the last of them; orgs may finish at once under --parallel-orgs
+type grandTotal struct {
+	mu          sync.Mutex
+	orgs        map[string]bool
+	commits     int
+	activeRepos int
+	top         *report
+}
+
+var runTotal grandTotal
+
+// add counts an org's summary, before --top leaves any repos out. The summary
+// of an owner/name repo counts towards its owner, so repos of one org given
+// one by one are still one org.
+func (g *grandTotal) add(summary *orgSummary) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.orgs == nil {
+		g.orgs = map[string]bool{}
+	}
+	owner, _, _ := strings.Cut(summary.Org, "/")
+	g.orgs[strings.ToLower(owner)] = true
+	g.commits += summary.Commits
+	g.activeRepos += summary.ActiveRepos
+	for _, r := range summary.Repos {
+		if g.top == nil || r.Summary > g.top.Summary {
+			g.top = r
+		}
+	}
+}
+
+// write prints the grand total, and the most active repo of all, once more
+// than one org has been reported on
+func (g *grandTotal) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.orgs) < 2 {
+		return
+	}
+
+	fmt.Fprintf(
+		w, "\nGrand total: %s commits across %s active repos in %d orgs\n",
+		humanize(g.commits), humanize(g.activeRepos), len(g.orgs),
+	)
+	if g.top != nil {
+		fmt.Fprintf(
+			w, "Most active: %s (%s commits)\n", g.top.Name,
+			humanize(g.top.Summary),
+		)
+	}
+}
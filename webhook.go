@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// postWebhook sends the org's report, in the same envelope as --format=json,
+// to the --webhook URL. It only warns when that fails, since the report has
+// already been printed.
+func postWebhook(url string, summary *orgSummary) {
+	var body bytes.Buffer
+	if err := writeJSON(&body, summary); err != nil {
+		log.Printf("Warning: encoding webhook for %s failed: %s", summary.Org, err)
+		return
+	}
+
+	req, err := http.NewRequest("POST", url, &body)
+	if err != nil {
+		log.Printf("Warning: webhook for %s failed: %s", summary.Org, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if *webhookHeader != "" {
+		name, value, _ := strings.Cut(*webhookHeader, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// Not doRequest: the GitHub token must not go to the webhook
+	resp, err := newClient().Do(req)
+	if err != nil {
+		log.Printf("Warning: webhook for %s failed: %s", summary.Org, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Printf(
+			"Warning: webhook for %s failed: %s", summary.Org, resp.Status,
+		)
+	}
+}
+
+// validWebhookHeader checks that --webhook-header looks like "Name: value"
+func validWebhookHeader(header string) error {
+	name, _, ok := strings.Cut(header, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("--webhook-header %q is not \"Name: value\"", header)
+	}
+	return nil
+}